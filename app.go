@@ -7,6 +7,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
@@ -14,6 +15,16 @@ import (
 const (
 	defaultConcurrency   = 30
 	defaultTimeoutSecond = 5
+
+	scanRowEventName      = "scan:row"
+	scanProgressEventName = "scan:progress"
+	scanJobEventName      = "scan:job"
+
+	// Queued jobs start while fewer than defaultMaxRunningJobs are running,
+	// and all of them together keep at most defaultScanBudget requests in
+	// flight.
+	defaultMaxRunningJobs = 2
+	defaultScanBudget     = 100
 )
 
 var removeInputFile = os.Remove
@@ -21,31 +32,138 @@ var removeInputFile = os.Remove
 // App struct
 type App struct {
 	ctx context.Context
+
+	mu         sync.Mutex
+	cancelScan context.CancelFunc
+	jobs       *jobStore
 }
 
 type ScanRequest struct {
-	InputFilePath        string `json:"inputFilePath"`
-	OutputDir            string `json:"outputDir"`
-	Concurrency          int    `json:"concurrency"`
-	TimeoutSeconds       int    `json:"timeoutSeconds"`
-	FollowRedirect       bool   `json:"followRedirect"`
-	DeleteSourceAfterRun bool   `json:"deleteSourceAfterRun"`
+	InputFilePath        string     `json:"inputFilePath"`
+	InputFilePaths       []string   `json:"inputFilePaths"`
+	InputPattern         string     `json:"inputPattern"`
+	RecursiveInput       bool       `json:"recursiveInput"`
+	ReportPerSource      bool       `json:"reportPerSource"`
+	OutputDir            string     `json:"outputDir"`
+	Concurrency          int        `json:"concurrency"`
+	TimeoutSeconds       int        `json:"timeoutSeconds"`
+	FollowRedirect       bool       `json:"followRedirect"`
+	DeleteSourceAfterRun bool       `json:"deleteSourceAfterRun"`
+	IncludeStatus        string     `json:"includeStatus"`
+	ExcludeStatus        string     `json:"excludeStatus"`
+	BaseURL              string     `json:"baseUrl"`
+	Mode                 string     `json:"mode"`
+	WordlistPath         string     `json:"wordlistPath"`
+	Extensions           string     `json:"extensions"`
+	ForceExtensions      bool       `json:"forceExtensions"`
+	ExcludeSizes         string     `json:"excludeSizes"`
+	DetectWildcard       bool       `json:"detectWildcard"`
+	DropWildcard         bool       `json:"dropWildcard"`
+	RequestsPerSecond    int        `json:"requestsPerSecond"`
+	PerHostConcurrency   int        `json:"perHostConcurrency"`
+	PerHostDelayMs       int        `json:"perHostDelayMs"`
+	PerHostJitterMs      int        `json:"perHostJitterMs"`
+	MaxRetries           int        `json:"maxRetries"`
+	RetryBackoffMs       int        `json:"retryBackoffMs"`
+	ProxyURL             string     `json:"proxyUrl"`
+	ProxyList            string     `json:"proxyList"`
+	ReplayProxyURL       string     `json:"replayProxyUrl"`
+	AuthRules            []AuthRule `json:"authRules"`
+	AuthProfile          string     `json:"authProfile"`
+	BrowserProfile       string     `json:"browserProfile"`
+	InsecureSkipVerify   bool       `json:"insecureSkipVerify"`
+	CABundlePath         string     `json:"caBundlePath"`
+	ClientCertPath       string     `json:"clientCertPath"`
+	ClientKeyPath        string     `json:"clientKeyPath"`
+	ClientCertPassword   string     `json:"clientCertPassword"`
+	DisableFavicon       bool       `json:"disableFavicon"`
+	FingerprintRulePaths string     `json:"fingerprintRulePaths"`
+
+	// gate, when set, paces the scan's requests; see scanGate.
+	gate *scanGate
+	// fingerprints is the engine newScanPlan compiled from
+	// FingerprintRulePaths; when nil the workers compile it themselves.
+	fingerprints *fingerprintEngine
 }
 
 type ScanRow struct {
-	URL        string   `json:"url"`
-	Title      string   `json:"title"`
-	Components []string `json:"components"`
-	Error      string   `json:"error"`
+	URL            string   `json:"url"`
+	FinalURL       string   `json:"finalUrl"`
+	StatusCode     int      `json:"statusCode"`
+	Title          string   `json:"title"`
+	ContentType    string   `json:"contentType"`
+	ContentLength  int64    `json:"contentLength"`
+	DeclaredLength int64    `json:"declaredLength"`
+	BodyTruncated  bool     `json:"bodyTruncated"`
+	ResponseTimeMs int64    `json:"responseTimeMs"`
+	Location       string   `json:"location"`
+	Words          int      `json:"words"`
+	Attempts       int      `json:"attempts"`
+	BrowserProfile string   `json:"browserProfile"`
+	Components     []string `json:"components"`
+	Error          string   `json:"error"`
+	SourceStatus   int      `json:"sourceStatus"`
+	SourceSize     int64    `json:"sourceSize"`
+	SourceRedirect string   `json:"sourceRedirect"`
+	SourceFiles    []string `json:"sourceFiles"`
+	WildcardMatch  bool     `json:"wildcardMatch"`
+
+	ComponentDetails []Component `json:"componentDetails"`
+
+	RedirectChain     []RedirectHop `json:"redirectChain"`
+	CrossHostRedirect bool          `json:"crossHostRedirect"`
+	OffScopeRedirect  bool          `json:"offScopeRedirect"`
+	LoginRedirect     bool          `json:"loginRedirect"`
+
+	TLS *TLSInfo `json:"tls,omitempty"`
+
+	FaviconURL  string `json:"faviconUrl"`
+	FaviconHash int32  `json:"faviconHash"`
+	FaviconMD5  string `json:"faviconMd5"`
+
+	bodyHash    string
+	pageIconURL string
+	evidence    *fingerprintEvidence
+	// fetchErr is the error behind Error when the request itself failed.
+	fetchErr error
+}
+
+// ScanRowEvent is emitted to the frontend as soon as a single URL finishes.
+// Index is the position of the URL in the parsed input.
+type ScanRowEvent struct {
+	Index int     `json:"index"`
+	Row   ScanRow `json:"row"`
+}
+
+// ScanProgress is the aggregate state of a running scan, emitted after every
+// finished row.
+type ScanProgress struct {
+	Done          int     `json:"done"`
+	Total         int     `json:"total"`
+	Succeeded     int     `json:"succeeded"`
+	Failed        int     `json:"failed"`
+	RatePerSecond float64 `json:"ratePerSecond"`
+	ETASeconds    int     `json:"etaSeconds"`
 }
 
 type ScanResponse struct {
-	ReportPath    string    `json:"reportPath"`
-	Total200Lines int       `json:"total200Lines"`
-	TotalURLs     int       `json:"totalUrls"`
-	Succeeded     int       `json:"succeeded"`
-	Failed        int       `json:"failed"`
-	Rows          []ScanRow `json:"rows"`
+	ReportPath        string             `json:"reportPath"`
+	ReportPaths       []string           `json:"reportPaths"`
+	Mode              string             `json:"mode"`
+	Wordlist          string             `json:"wordlist"`
+	InputFormat       string             `json:"inputFormat"`
+	InputFiles        []InputFileSummary `json:"inputFiles"`
+	SkippedFiles      []string           `json:"skippedFiles"`
+	StatusFilter      string             `json:"statusFilter"`
+	TotalMatchedLines int                `json:"totalMatchedLines"`
+	TotalURLs         int                `json:"totalUrls"`
+	Succeeded         int                `json:"succeeded"`
+	Failed            int                `json:"failed"`
+	WildcardMatches   int                `json:"wildcardMatches"`
+	Replayed          int                `json:"replayed"`
+	SANPivots         []string           `json:"sanPivots"`
+	Cancelled         bool               `json:"cancelled"`
+	Rows              []ScanRow          `json:"rows"`
 }
 
 // NewApp creates a new App application struct
@@ -72,7 +190,62 @@ func (a *App) SelectInputFile() (string, error) {
 	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
 		Title: "\u9009\u62e9 URL \u6e90\u6587\u672c\u6587\u4ef6",
 		Filters: []runtime.FileFilter{
-			{DisplayName: "\u6587\u672c\u6587\u4ef6", Pattern: "*.txt;*.log;*.md;*.csv"},
+			{DisplayName: "\u6587\u672c\u6587\u4ef6", Pattern: defaultInputPattern},
+			{DisplayName: "\u6240\u6709\u6587\u4ef6", Pattern: "*.*"},
+		},
+	})
+}
+
+// SelectInputFiles picks several input files for one batch scan.
+func (a *App) SelectInputFiles() ([]string, error) {
+	if a.ctx == nil {
+		return nil, errors.New("\u5e94\u7528\u4e0a\u4e0b\u6587\u5c1a\u672a\u521d\u59cb\u5316")
+	}
+
+	return runtime.OpenMultipleFilesDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "\u9009\u62e9\u591a\u4e2a URL \u6e90\u6587\u672c\u6587\u4ef6",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "\u6587\u672c\u6587\u4ef6", Pattern: defaultInputPattern},
+			{DisplayName: "\u6240\u6709\u6587\u4ef6", Pattern: "*.*"},
+		},
+	})
+}
+
+// SelectInputDirectory picks a directory whose files are scanned as one
+// batch.
+func (a *App) SelectInputDirectory() (string, error) {
+	if a.ctx == nil {
+		return "", errors.New("\u5e94\u7528\u4e0a\u4e0b\u6587\u5c1a\u672a\u521d\u59cb\u5316")
+	}
+
+	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "\u9009\u62e9\u8f93\u5165\u76ee\u5f55",
+	})
+}
+
+func (a *App) SelectWordlistFile() (string, error) {
+	if a.ctx == nil {
+		return "", errors.New("\u5e94\u7528\u4e0a\u4e0b\u6587\u5c1a\u672a\u521d\u59cb\u5316")
+	}
+
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "\u9009\u62e9\u5b57\u5178\u6587\u4ef6",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "\u5b57\u5178\u6587\u4ef6", Pattern: "*.txt;*.lst;*.dic"},
+			{DisplayName: "\u6240\u6709\u6587\u4ef6", Pattern: "*.*"},
+		},
+	})
+}
+
+func (a *App) SelectCertificateFile() (string, error) {
+	if a.ctx == nil {
+		return "", errors.New("\u5e94\u7528\u4e0a\u4e0b\u6587\u5c1a\u672a\u521d\u59cb\u5316")
+	}
+
+	return runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "\u9009\u62e9\u8bc1\u4e66\u6587\u4ef6",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "\u8bc1\u4e66\u6587\u4ef6", Pattern: "*.pem;*.crt;*.cer;*.key;*.p12;*.pfx"},
 			{DisplayName: "\u6240\u6709\u6587\u4ef6", Pattern: "*.*"},
 		},
 	})
@@ -91,49 +264,216 @@ func (a *App) SelectOutputDirectory() (string, error) {
 func (a *App) RunScan(request ScanRequest) (ScanResponse, error) {
 	request = normalizeScanRequest(request)
 
-	if request.InputFilePath == "" {
-		return ScanResponse{}, errors.New("\u8bf7\u8f93\u5165\u8f93\u5165\u6587\u4ef6\u8def\u5f84")
+	plan, err := prepareScan(request)
+	if err != nil {
+		return ScanResponse{}, err
+	}
+
+	scanCtx, err := a.beginScan()
+	if err != nil {
+		return ScanResponse{}, err
 	}
+	defer a.endScan()
 
-	urls, totalMatchedLines, err := parseInputFile(request.InputFilePath)
+	response, err := plan.run(scanCtx, a.emitScanRow, a.emitProgress)
 	if err != nil {
 		return ScanResponse{}, err
 	}
 
-	response := ScanResponse{
-		Total200Lines: totalMatchedLines,
-		TotalURLs:     len(urls),
+	if err := finishScan(plan.request, &response); err != nil {
+		return ScanResponse{}, err
+	}
+
+	return response, nil
+}
+
+// CancelScan aborts the scan started by RunScan. RunScan still returns the
+// rows that finished before cancellation and writes them to the report.
+func (a *App) CancelScan() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelScan == nil {
+		return errors.New("\u5f53\u524d\u6ca1\u6709\u6b63\u5728\u8fd0\u884c\u7684\u626b\u63cf\u4efb\u52a1")
+	}
+
+	a.cancelScan()
+	return nil
+}
+
+// EnqueueScan validates request and adds it to the job queue. Progress is
+// reported through scan:job events rather than scan:row and scan:progress.
+func (a *App) EnqueueScan(request ScanRequest) (ScanJob, error) {
+	plan, err := prepareScan(normalizeScanRequest(request))
+	if err != nil {
+		return ScanJob{}, err
 	}
+	return a.jobQueue().submit(plan).snapshot(), nil
+}
 
-	if len(urls) > 0 {
-		response.Rows = runScanWorkers(urls, request)
-		for _, row := range response.Rows {
-			if row.Error == "" {
-				response.Succeeded++
-			} else {
-				response.Failed++
-			}
+// ListScanJobs returns the queued, running and completed jobs, oldest first.
+func (a *App) ListScanJobs() []ScanJob {
+	return a.jobQueue().list()
+}
+
+// PauseScanJob stops a job from sending new requests; requests in flight
+// still finish.
+func (a *App) PauseScanJob(id string) error {
+	return a.controlScanJob(id, (*jobStore).pause)
+}
+
+// ResumeScanJob continues a paused job.
+func (a *App) ResumeScanJob(id string) error {
+	return a.controlScanJob(id, (*jobStore).resume)
+}
+
+// CancelScanJob stops a job. A running job still writes the rows that
+// finished to its report.
+func (a *App) CancelScanJob(id string) error {
+	return a.controlScanJob(id, (*jobStore).cancel)
+}
+
+// ScanJobResult returns the response of a finished or cancelled job.
+func (a *App) ScanJobResult(id string) (ScanResponse, error) {
+	job, ok := a.jobQueue().get(id)
+	if !ok {
+		return ScanResponse{}, errors.New("\u626b\u63cf\u4efb\u52a1\u4e0d\u5b58\u5728")
+	}
+	response, ok := job.result()
+	if !ok {
+		snapshot := job.snapshot()
+		if snapshot.CancelledBeforeStart {
+			return ScanResponse{}, errors.New("\u626b\u63cf\u4efb\u52a1\u5728\u5f00\u59cb\u524d\u5df2\u53d6\u6d88")
+		}
+		if snapshot.Error != "" {
+			return ScanResponse{}, errors.New(snapshot.Error)
 		}
+		return ScanResponse{}, errors.New("\u626b\u63cf\u4efb\u52a1\u5c1a\u672a\u7ed3\u675f")
 	}
+	return response, nil
+}
 
-	outputDir := strings.TrimSpace(request.OutputDir)
-	if outputDir == "" {
-		outputDir = filepath.Dir(request.InputFilePath)
+// SetScanJobLimits sets how many jobs run at once and how many requests they
+// may have in flight together.
+func (a *App) SetScanJobLimits(maxRunningJobs, maxRequests int) error {
+	if maxRunningJobs <= 0 || maxRequests <= 0 {
+		return errors.New("\u4efb\u52a1\u6570\u548c\u5e76\u53d1\u8bf7\u6c42\u6570\u5fc5\u987b\u5927\u4e8e 0")
 	}
+	a.jobQueue().setLimits(maxRunningJobs, maxRequests)
+	return nil
+}
 
-	reportPath := filepath.Join(outputDir, buildReportFileName(request.InputFilePath))
-	if err := appendMarkdownReport(reportPath, request.InputFilePath, response); err != nil {
-		return ScanResponse{}, err
+func (a *App) jobQueue() *jobStore {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.jobs == nil {
+		parent := a.ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		a.jobs = newJobStore(parent)
+		a.jobs.onChange = a.emitScanJob
+		a.jobs.setLimits(defaultMaxRunningJobs, defaultScanBudget)
 	}
+	return a.jobs
+}
 
-	response.ReportPath = reportPath
-	if request.DeleteSourceAfterRun {
-		if err := removeInputFile(request.InputFilePath); err != nil {
-			return ScanResponse{}, fmt.Errorf("\u5220\u9664\u6e90\u6587\u4ef6\u5931\u8d25: %w", err)
+func (a *App) controlScanJob(id string, control func(*jobStore, *scanJob) error) error {
+	jobs := a.jobQueue()
+	job, ok := jobs.get(id)
+	if !ok {
+		return errors.New("\u626b\u63cf\u4efb\u52a1\u4e0d\u5b58\u5728")
+	}
+	if err := control(jobs, job); err != nil {
+		if errors.Is(err, errJobDone) {
+			return errors.New("\u626b\u63cf\u4efb\u52a1\u5df2\u7ed3\u675f")
 		}
+		return err
+	}
+	return nil
+}
+
+// ListBrowserProfiles returns the names of the built-in browser header sets.
+func (a *App) ListBrowserProfiles() []string {
+	return browserProfileNames()
+}
+
+// ListAuthProfiles returns the saved authentication profiles.
+func (a *App) ListAuthProfiles() ([]AuthProfile, error) {
+	return loadAuthProfiles()
+}
+
+// SaveAuthProfile creates or replaces the profile with the same name.
+func (a *App) SaveAuthProfile(profile AuthProfile) error {
+	profile.Name = strings.TrimSpace(profile.Name)
+	if profile.Name == "" {
+		return errors.New("\u8bf7\u8f93\u5165\u8ba4\u8bc1\u914d\u7f6e\u540d\u79f0")
 	}
 
-	return response, nil
+	if err := upsertAuthProfile(profile); err != nil {
+		return fmt.Errorf("\u4fdd\u5b58\u8ba4\u8bc1\u914d\u7f6e\u5931\u8d25: %w", err)
+	}
+	return nil
+}
+
+// DeleteAuthProfile removes the named profile.
+func (a *App) DeleteAuthProfile(name string) error {
+	if err := removeAuthProfile(strings.TrimSpace(name)); err != nil {
+		return fmt.Errorf("\u5220\u9664\u8ba4\u8bc1\u914d\u7f6e\u5931\u8d25: %w", err)
+	}
+	return nil
+}
+
+func (a *App) beginScan() (context.Context, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelScan != nil {
+		return nil, errors.New("\u5df2\u6709\u626b\u63cf\u4efb\u52a1\u6b63\u5728\u8fd0\u884c")
+	}
+
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+
+	ctx, cancel := context.WithCancel(parent)
+	a.cancelScan = cancel
+	return ctx, nil
+}
+
+func (a *App) endScan() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.cancelScan != nil {
+		a.cancelScan()
+		a.cancelScan = nil
+	}
+}
+
+// emitScanRow, emitProgress and emitScanJob forward per-row results, aggregate
+// progress and job updates to the frontend. They are no-ops outside the Wails runtime, e.g. in tests.
+func (a *App) emitScanRow(event ScanRowEvent) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, scanRowEventName, event)
+}
+
+func (a *App) emitProgress(progress ScanProgress) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, scanProgressEventName, progress)
+}
+
+func (a *App) emitScanJob(job ScanJob) {
+	if a.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(a.ctx, scanJobEventName, job)
 }
 
 func normalizeScanRequest(request ScanRequest) ScanRequest {
@@ -153,6 +493,43 @@ func normalizeScanRequest(request ScanRequest) ScanRequest {
 		request.TimeoutSeconds = 120
 	}
 
+	if request.RequestsPerSecond < 0 {
+		request.RequestsPerSecond = 0
+	}
+
+	if request.PerHostConcurrency < 0 {
+		request.PerHostConcurrency = 0
+	}
+
+	if request.PerHostDelayMs < 0 {
+		request.PerHostDelayMs = 0
+	}
+
+	if request.PerHostJitterMs < 0 {
+		request.PerHostJitterMs = 0
+	}
+
+	if request.MaxRetries < 0 {
+		request.MaxRetries = 0
+	}
+
+	if request.MaxRetries > maxRetries {
+		request.MaxRetries = maxRetries
+	}
+
+	if request.RetryBackoffMs <= 0 {
+		request.RetryBackoffMs = defaultRetryBackoffMs
+	}
+
+	request.Mode = strings.ToLower(strings.TrimSpace(request.Mode))
+	if request.Mode != scanModeDiscover {
+		request.Mode = scanModeImport
+	}
+
+	if strings.TrimSpace(request.IncludeStatus) == "" {
+		request.IncludeStatus = defaultIncludeStatus
+	}
+
 	return request
 }
 
@@ -160,6 +537,9 @@ func buildReportFileName(inputFilePath string) string {
 	baseName := filepath.Base(inputFilePath)
 	ext := filepath.Ext(baseName)
 	nameWithoutExt := strings.TrimSuffix(baseName, ext)
+	if inputFilePath == stdinInputPath {
+		nameWithoutExt = "stdin"
+	}
 	if nameWithoutExt == "" {
 		nameWithoutExt = "scan"
 	}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestRunScanCreatesMarkdownReport(t *testing.T) {
@@ -49,7 +50,7 @@ func TestRunScanCreatesMarkdownReport(t *testing.T) {
 		t.Fatalf("read report: %v", err)
 	}
 	report := string(reportBytes)
-	if !strings.Contains(report, "| URL | Title | Components | Error |") {
+	if !strings.Contains(report, "| URL | Source | Status | Title | Components | Content-Type | Length | Time | Location | Error |") {
 		t.Fatalf("report table header missing: %s", report)
 	}
 	if !strings.Contains(report, server.URL+"/home") {
@@ -212,3 +213,194 @@ func TestRunScanReturnsErrorWhenDeleteFails(t *testing.T) {
 		t.Fatalf("expected input file to remain after delete failure: %v", statErr)
 	}
 }
+
+func TestCancelScanWritesPartialReport(t *testing.T) {
+	slowStarted, busyAnswered := make(chan struct{}), make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			close(slowStarted)
+			<-r.Context().Done()
+			return
+		case "/busy":
+			// The scan is cancelled while it waits to retry this answer.
+			w.WriteHeader(http.StatusServiceUnavailable)
+			close(busyAnswered)
+			return
+		}
+		_, _ = w.Write([]byte("<html><head><title>Fast</title></head><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "source.txt")
+	input := "200 120B 0.001s " + server.URL + "/busy\n" +
+		"200 120B 0.001s " + server.URL + "/fast\n" +
+		"200 120B 0.001s " + server.URL + "/slow\n" +
+		"200 120B 0.001s " + server.URL + "/never\n"
+	if err := os.WriteFile(inputPath, []byte(input), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	app := NewApp()
+	if err := app.CancelScan(); err == nil {
+		t.Fatalf("expected error when no scan is running")
+	}
+
+	type scanResult struct {
+		response ScanResponse
+		err      error
+	}
+	done := make(chan scanResult, 1)
+	go func() {
+		response, err := app.RunScan(ScanRequest{
+			InputFilePath:        inputPath,
+			Concurrency:          2,
+			TimeoutSeconds:       30,
+			MaxRetries:           1,
+			RetryBackoffMs:       30000,
+			DeleteSourceAfterRun: true,
+		})
+		done <- scanResult{response: response, err: err}
+	}()
+
+	<-slowStarted
+	<-busyAnswered
+	// Let the client read the 503 before cancelling.
+	time.Sleep(50 * time.Millisecond)
+	if err := app.CancelScan(); err != nil {
+		t.Fatalf("cancel scan: %v", err)
+	}
+
+	result := <-done
+	if result.err != nil {
+		t.Fatalf("run scan: %v", result.err)
+	}
+	if !result.response.Cancelled {
+		t.Fatalf("expected cancelled response: %+v", result.response)
+	}
+	rows := result.response.Rows
+	if len(rows) != 2 || rows[0].URL != server.URL+"/busy" || rows[0].StatusCode != http.StatusServiceUnavailable || rows[1].URL != server.URL+"/fast" {
+		t.Fatalf("expected only the finished rows, including the failed one, got %+v", rows)
+	}
+
+	reportBytes, err := os.ReadFile(result.response.ReportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	report := string(reportBytes)
+	if !strings.Contains(report, "Cancelled") {
+		t.Fatalf("expected cancelled marker in report: %s", report)
+	}
+	if strings.Contains(report, "/slow") || strings.Contains(report, "/never") {
+		t.Fatalf("expected unfinished URLs to be omitted: %s", report)
+	}
+	if _, err := os.Stat(inputPath); err != nil {
+		t.Fatalf("expected input file to remain after cancellation: %v", err)
+	}
+}
+
+func TestRunScanDiscoverModeReportsOnlyHits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/app/admin.php":
+			_, _ = w.Write([]byte("<html><head><title>Admin</title></head><body>wp-content</body></html>"))
+		case "/app/backup/":
+			w.WriteHeader(http.StatusForbidden)
+		case "/app/empty":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	targetsPath := filepath.Join(tempDir, "targets.txt")
+	if err := os.WriteFile(targetsPath, []byte("# targets\n"+server.URL+"/app/\n"), 0o644); err != nil {
+		t.Fatalf("write targets: %v", err)
+	}
+	wordlistPath := filepath.Join(tempDir, "words.txt")
+	if err := os.WriteFile(wordlistPath, []byte("admin.%EXT%\nbackup/\nempty\nmissing\n"), 0o644); err != nil {
+		t.Fatalf("write wordlist: %v", err)
+	}
+
+	app := NewApp()
+	result, err := app.RunScan(ScanRequest{
+		Mode:           scanModeDiscover,
+		InputFilePath:  targetsPath,
+		WordlistPath:   wordlistPath,
+		Extensions:     "php,asp",
+		ExcludeSizes:   "0",
+		Concurrency:    5,
+		TimeoutSeconds: 5,
+	})
+	if err != nil {
+		t.Fatalf("run scan: %v", err)
+	}
+
+	if result.TotalURLs != 5 {
+		t.Fatalf("expected 5 candidate URLs, got %d", result.TotalURLs)
+	}
+	if result.TotalMatchedLines != 1 || len(result.Rows) != 1 {
+		t.Fatalf("expected a single hit, got %+v", result.Rows)
+	}
+	if result.Rows[0].URL != server.URL+"/app/admin.php" || result.Rows[0].Title != "Admin" {
+		t.Fatalf("unexpected hit %+v", result.Rows[0])
+	}
+
+	reportBytes, err := os.ReadFile(filepath.Join(tempDir, "targets_report.md"))
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	report := string(reportBytes)
+	if !strings.Contains(report, "- Mode: discover") || !strings.Contains(report, "Total Hits (200/301/403): 1") {
+		t.Fatalf("expected discovery summary in report: %s", report)
+	}
+}
+
+func TestEnqueueScanRunsJobsInTheBackground(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><head><title>Queued</title></head></html>"))
+	}))
+	defer server.Close()
+
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "queued.txt")
+	if err := os.WriteFile(inputPath, []byte("200 "+server.URL+"/a\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+
+	app := NewApp()
+	if _, err := app.EnqueueScan(ScanRequest{InputFilePath: filepath.Join(tempDir, "missing.txt")}); err == nil {
+		t.Fatal("expected an invalid request to be rejected before queueing")
+	}
+
+	job, err := app.EnqueueScan(ScanRequest{InputFilePath: inputPath, DisableFavicon: true})
+	if err != nil {
+		t.Fatalf("enqueue scan: %v", err)
+	}
+	if jobs := app.ListScanJobs(); len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("expected the job in the list, got %+v", jobs)
+	}
+
+	stored, _ := app.jobQueue().get(job.ID)
+	<-stored.done
+	result, err := app.ScanJobResult(job.ID)
+	if err != nil {
+		t.Fatalf("job result: %v", err)
+	}
+	if len(result.Rows) != 1 || result.Rows[0].Title != "Queued" || result.ReportPath != filepath.Join(tempDir, "queued_report.md") {
+		t.Fatalf("unexpected job result %+v", result)
+	}
+
+	if err := app.PauseScanJob(job.ID); err == nil || !strings.Contains(err.Error(), "已结束") {
+		t.Fatalf("expected pausing a finished job to fail, got %v", err)
+	}
+	if err := app.CancelScanJob("unknown"); err == nil {
+		t.Fatal("expected an unknown job to be rejected")
+	}
+	if err := app.SetScanJobLimits(0, 10); err == nil {
+		t.Fatal("expected invalid limits to be rejected")
+	}
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// AuthRule adds headers, cookies and credentials to requests whose host
+// matches HostPattern, a glob such as "*.example.com" or "10.0.0.5:8443".
+// An empty pattern matches every host. Headers holds one "Name: value" per
+// line; Cookies is a raw Cookie header value.
+type AuthRule struct {
+	HostPattern   string `json:"hostPattern"`
+	Headers       string `json:"headers"`
+	Cookies       string `json:"cookies"`
+	BasicUser     string `json:"basicUser"`
+	BasicPassword string `json:"basicPassword"`
+	BearerToken   string `json:"bearerToken"`
+}
+
+// AuthProfile is a named set of auth rules saved between sessions.
+type AuthProfile struct {
+	Name  string     `json:"name"`
+	Rules []AuthRule `json:"rules"`
+}
+
+// authProfileStorePath is a variable so tests can point it at a temp dir.
+var authProfileStorePath = func() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "handlerdirsearch", "auth-profiles.json"), nil
+}
+
+type headerField struct {
+	name  string
+	value string
+}
+
+type compiledAuthRule struct {
+	pattern string
+	headers []headerField
+}
+
+// authRules are compiled once per scan and applied to every request.
+type authRules []compiledAuthRule
+
+func compileAuthRules(rules []AuthRule) (authRules, error) {
+	compiled := make(authRules, 0, len(rules))
+	for _, rule := range rules {
+		pattern := strings.ToLower(strings.TrimSpace(rule.HostPattern))
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid host pattern %q: %w", rule.HostPattern, err)
+		}
+
+		headers, err := parseHeaderLines(rule.Headers)
+		if err != nil {
+			return nil, err
+		}
+		if cookies := strings.TrimSpace(rule.Cookies); cookies != "" {
+			headers = append(headers, headerField{name: "Cookie", value: strings.TrimPrefix(cookies, "Cookie:")})
+		}
+		if rule.BasicUser != "" || rule.BasicPassword != "" {
+			credentials := base64.StdEncoding.EncodeToString([]byte(rule.BasicUser + ":" + rule.BasicPassword))
+			headers = append(headers, headerField{name: "Authorization", value: "Basic " + credentials})
+		}
+		if token := strings.TrimSpace(rule.BearerToken); token != "" {
+			headers = append(headers, headerField{name: "Authorization", value: "Bearer " + token})
+		}
+
+		if len(headers) > 0 {
+			compiled = append(compiled, compiledAuthRule{pattern: pattern, headers: headers})
+		}
+	}
+	return compiled, nil
+}
+
+func parseHeaderLines(spec string) ([]headerField, error) {
+	headers := make([]headerField, 0)
+	for _, line := range strings.Split(spec, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", line)
+		}
+		headers = append(headers, headerField{name: name, value: strings.TrimSpace(value)})
+	}
+	return headers, nil
+}
+
+// apply sets the headers of every matching rule on req. Later rules override
+// earlier ones, so a catch-all rule can be refined per host.
+func (rules authRules) apply(req *http.Request) {
+	host := strings.ToLower(req.URL.Host)
+	hostname := strings.ToLower(req.URL.Hostname())
+	for _, rule := range rules {
+		if !rule.matches(host, hostname) {
+			continue
+		}
+		for _, header := range rule.headers {
+			req.Header.Set(header.name, strings.TrimSpace(header.value))
+		}
+	}
+}
+
+// redirect re-applies the rules to the next hop of a redirect. The client
+// copies the previous hop's headers, so those set by rules that do not match
+// the new host are removed before the matching rules are applied.
+func (rules authRules) redirect(req *http.Request) {
+	host := strings.ToLower(req.URL.Host)
+	hostname := strings.ToLower(req.URL.Hostname())
+	for _, rule := range rules {
+		if rule.matches(host, hostname) {
+			continue
+		}
+		for _, header := range rule.headers {
+			if req.Header.Get(header.name) == strings.TrimSpace(header.value) {
+				req.Header.Del(header.name)
+			}
+		}
+	}
+	rules.apply(req)
+}
+
+func (rule compiledAuthRule) matches(host, hostname string) bool {
+	if rule.pattern == "" || rule.pattern == "*" {
+		return true
+	}
+	if matched, _ := path.Match(rule.pattern, host); matched {
+		return true
+	}
+	matched, _ := path.Match(rule.pattern, hostname)
+	return matched
+}
+
+// resolveAuthRules returns the rules of the request's saved profile, if any,
+// followed by the rules entered for this scan.
+func resolveAuthRules(request ScanRequest) ([]AuthRule, error) {
+	name := strings.TrimSpace(request.AuthProfile)
+	if name == "" {
+		return request.AuthRules, nil
+	}
+
+	profiles, err := loadAuthProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return append(append([]AuthRule(nil), profile.Rules...), request.AuthRules...), nil
+		}
+	}
+	return nil, fmt.Errorf("auth profile %q not found", name)
+}
+
+func loadAuthProfiles() ([]AuthProfile, error) {
+	storePath, err := authProfileStorePath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return []AuthProfile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read auth profiles: %w", err)
+	}
+
+	profiles := make([]AuthProfile, 0)
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("parse auth profiles: %w", err)
+	}
+	return profiles, nil
+}
+
+// saveAuthProfiles writes the profiles readable only by the current user,
+// since they hold credentials.
+func saveAuthProfiles(profiles []AuthProfile) error {
+	storePath, err := authProfileStorePath()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(storePath), 0o700); err != nil {
+		return fmt.Errorf("create config directory: %w", err)
+	}
+	if err := os.WriteFile(storePath, data, 0o600); err != nil {
+		return fmt.Errorf("write auth profiles: %w", err)
+	}
+	return nil
+}
+
+func upsertAuthProfile(profile AuthProfile) error {
+	if _, err := compileAuthRules(profile.Rules); err != nil {
+		return err
+	}
+
+	profiles, err := loadAuthProfiles()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i := range profiles {
+		if profiles[i].Name == profile.Name {
+			profiles[i] = profile
+			replaced = true
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+	return saveAuthProfiles(profiles)
+}
+
+func removeAuthProfile(name string) error {
+	profiles, err := loadAuthProfiles()
+	if err != nil {
+		return err
+	}
+
+	kept := make([]AuthProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		if profile.Name != name {
+			kept = append(kept, profile)
+		}
+	}
+	return saveAuthProfiles(kept)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScanWorkersAppliesAuthRulesPerHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "abc" || r.Header.Get("Authorization") != "Bearer token-1" || r.Header.Get("X-Team") != "red" {
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+		_, _ = w.Write([]byte("<title>dashboard</title>"))
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/admin")}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		AuthRules: []AuthRule{
+			{HostPattern: "*", Headers: "X-Team: blue"},
+			{HostPattern: "127.0.0.1", Headers: "X-Team: red", Cookies: "session=abc", BearerToken: "token-1"},
+			{HostPattern: "*.example.com", Headers: "X-Team: green"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	if len(rows) != 1 || rows[0].StatusCode != http.StatusOK || rows[0].Title != "dashboard" {
+		t.Fatalf("expected authenticated 200, got %+v", rows)
+	}
+}
+
+func TestAuthRulesAreReappliedOnCrossHostRedirects(t *testing.T) {
+	landing := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/landing" {
+			landing <- r.Header.Clone()
+			_, _ = w.Write([]byte("<title>landing</title>"))
+			return
+		}
+		// The same server under another host name.
+		_, port, _ := net.SplitHostPort(r.Host)
+		http.Redirect(w, r, "http://localhost:"+port+"/landing", http.StatusFound)
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/start")}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		FollowRedirect: true,
+		DisableFavicon: true,
+		AuthRules: []AuthRule{
+			{HostPattern: "127.0.0.1*", Headers: "X-Api-Key: secret", Cookies: "session=abc"},
+			{HostPattern: "localhost*", Headers: "X-Team: landing"},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].Title != "landing" {
+		t.Fatalf("expected the redirect to be followed, got %+v", rows)
+	}
+
+	headers := <-landing
+	if headers.Get("X-Api-Key") != "" || headers.Get("Cookie") != "" {
+		t.Fatalf("expected the 127.0.0.1 rule to stay off localhost, got %v", headers)
+	}
+	if headers.Get("X-Team") != "landing" {
+		t.Fatalf("expected the localhost rule on the redirected request, got %v", headers)
+	}
+}
+
+func TestCompileAuthRulesBuildsBasicAuthAndRejectsBadHeaders(t *testing.T) {
+	rules, err := compileAuthRules([]AuthRule{{BasicUser: "admin", BasicPassword: "s3cret"}})
+	if err != nil {
+		t.Fatalf("compileAuthRules returned error: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://intranet.local/", nil)
+	rules.apply(req)
+	if user, password, ok := req.BasicAuth(); !ok || user != "admin" || password != "s3cret" {
+		t.Fatalf("expected basic auth admin:s3cret, got %q %q %v", user, password, ok)
+	}
+
+	if _, err := compileAuthRules([]AuthRule{{Headers: "no colon here"}}); err == nil {
+		t.Fatal("expected header without colon to be rejected")
+	}
+	if _, err := compileAuthRules([]AuthRule{{HostPattern: "[", Headers: "X: y"}}); err == nil {
+		t.Fatal("expected malformed host pattern to be rejected")
+	}
+}
+
+func TestAuthProfilesRoundTrip(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "config", "auth-profiles.json")
+	previous := authProfileStorePath
+	authProfileStorePath = func() (string, error) { return storePath, nil }
+	defer func() { authProfileStorePath = previous }()
+
+	app := NewApp()
+	if err := app.SaveAuthProfile(AuthProfile{Name: " staging ", Rules: []AuthRule{{HostPattern: "*.staging.local", Cookies: "sid=1"}}}); err != nil {
+		t.Fatalf("SaveAuthProfile returned error: %v", err)
+	}
+	if err := app.SaveAuthProfile(AuthProfile{Name: "staging", Rules: []AuthRule{{BearerToken: "t"}}}); err != nil {
+		t.Fatalf("SaveAuthProfile returned error: %v", err)
+	}
+	if err := app.SaveAuthProfile(AuthProfile{Name: "prod", Rules: []AuthRule{{Cookies: "sid=2"}}}); err != nil {
+		t.Fatalf("SaveAuthProfile returned error: %v", err)
+	}
+
+	profiles, err := app.ListAuthProfiles()
+	if err != nil {
+		t.Fatalf("ListAuthProfiles returned error: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "prod" || profiles[1].Name != "staging" || profiles[1].Rules[0].BearerToken != "t" {
+		t.Fatalf("unexpected profiles %+v", profiles)
+	}
+
+	rules, err := resolveAuthRules(ScanRequest{AuthProfile: "staging", AuthRules: []AuthRule{{Headers: "X-Extra: 1"}}})
+	if err != nil || len(rules) != 2 || rules[0].BearerToken != "t" || rules[1].Headers != "X-Extra: 1" {
+		t.Fatalf("expected profile rules followed by inline rules, got %+v %v", rules, err)
+	}
+	if _, err := resolveAuthRules(ScanRequest{AuthProfile: "missing"}); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("expected missing profile error, got %v", err)
+	}
+
+	if err := app.DeleteAuthProfile("prod"); err != nil {
+		t.Fatalf("DeleteAuthProfile returned error: %v", err)
+	}
+	profiles, _ = app.ListAuthProfiles()
+	if len(profiles) != 1 || profiles[0].Name != "staging" {
+		t.Fatalf("expected only staging to remain, got %+v", profiles)
+	}
+
+	if err := app.SaveAuthProfile(AuthProfile{Name: "  "}); err == nil {
+		t.Fatal("expected empty profile name to be rejected")
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+const (
+	// defaultInputPattern picks the files of an input directory, like the
+	// input file dialog does.
+	defaultInputPattern = "*.txt;*.log;*.md;*.csv;*.json;*.jsonl;*.xml"
+
+	// batchInputName names the merged report of a list of input files.
+	batchInputName = "batch"
+)
+
+// InputFileSummary is what one file of a batch scan contributed. URLs counts
+// the file's URLs including those an earlier file already had.
+type InputFileSummary struct {
+	Path         string `json:"path"`
+	Format       string `json:"format"`
+	MatchedLines int    `json:"matchedLines"`
+	URLs         int    `json:"urls"`
+}
+
+// isBatchInput reports whether request names several input files or a
+// directory rather than one file.
+func isBatchInput(request ScanRequest) bool {
+	if len(request.InputFilePaths) > 0 {
+		return true
+	}
+	info, err := os.Stat(request.InputFilePath)
+	return err == nil && info.IsDir()
+}
+
+// expandInputFiles resolves the input paths of a batch request. Files are
+// kept as given; directories are replaced by the files in them that match
+// InputPattern, including subdirectories when RecursiveInput is set. Every
+// file is returned once, in order. Files of a directory named like the
+// reports and exports a scan of it writes are returned as skipped instead.
+func expandInputFiles(request ScanRequest) ([]string, []string, error) {
+	patterns, err := parseInputPatterns(request.InputPattern)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		files   []string
+		skipped []string
+		seen    = make(map[string]struct{})
+	)
+	add := func(path string) {
+		path = filepath.Clean(path)
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			files = append(files, path)
+		}
+	}
+
+	for _, root := range batchInputPaths(request) {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("\u8bfb\u53d6\u8f93\u5165\u6587\u4ef6\u5931\u8d25: %w", err)
+		}
+		if !info.IsDir() {
+			add(root)
+			continue
+		}
+
+		var found []string
+		err = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() {
+				if path != root && !request.RecursiveInput {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if matchesInputPattern(entry.Name(), patterns) {
+				found = append(found, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("\u8bfb\u53d6\u8f93\u5165\u76ee\u5f55\u5931\u8d25: %w", err)
+		}
+
+		// A directory scanned twice must not read its own reports back.
+		generated := generatedReportNames(append([]string{root, batchInputName}, found...)...)
+		matched := 0
+		for _, path := range found {
+			if _, ok := generated[filepath.Base(path)]; ok {
+				skipped = append(skipped, path)
+				continue
+			}
+			add(path)
+			matched++
+		}
+		if matched == 0 {
+			return nil, nil, fmt.Errorf("\u76ee\u5f55\u4e2d\u6ca1\u6709\u5339\u914d\u7684\u8f93\u5165\u6587\u4ef6: %s", root)
+		}
+	}
+	return files, skipped, nil
+}
+
+func batchInputPaths(request ScanRequest) []string {
+	var paths []string
+	for _, path := range append([]string{request.InputFilePath}, request.InputFilePaths...) {
+		if path = strings.TrimSpace(path); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// parseInputPatterns splits a list of file name globs separated by ";" or
+// ",", such as "*.txt;*.log".
+func parseInputPatterns(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		spec = defaultInputPattern
+	}
+
+	var patterns []string
+	for _, pattern := range strings.FieldsFunc(spec, func(r rune) bool { return r == ';' || r == ',' }) {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("\u8f93\u5165\u6587\u4ef6\u5339\u914d\u89c4\u5219\u65e0\u6548: %q", pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func matchesInputPattern(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// generatedReportNames returns the file names of the Markdown reports and
+// the exports written for scans of inputPaths.
+func generatedReportNames(inputPaths ...string) map[string]struct{} {
+	names := make(map[string]struct{})
+	for _, path := range inputPaths {
+		names[buildReportFileName(path)] = struct{}{}
+		for _, format := range []string{reportFormatJSON, reportFormatCSV, reportFormatJSONLines} {
+			names[exportFileName(path, format)] = struct{}{}
+		}
+	}
+	return names
+}
+
+// parseInputFiles parses every file and merges their entries. A URL found in
+// several files is kept once, with the status of its first occurrence, and
+// records every file it appeared in.
+func parseInputFiles(files []string, options inputOptions) (inputParseResult, []InputFileSummary, error) {
+	var (
+		result    inputParseResult
+		summaries []InputFileSummary
+		formats   []string
+		index     = make(map[string]int)
+	)
+	for _, file := range files {
+		parsed, err := parseInputFile(file, options)
+		if err != nil {
+			return inputParseResult{}, nil, fmt.Errorf("%s: %w", file, err)
+		}
+
+		summaries = append(summaries, InputFileSummary{
+			Path:         file,
+			Format:       parsed.Format,
+			MatchedLines: parsed.MatchedLines,
+			URLs:         len(parsed.Entries),
+		})
+		if !slices.Contains(formats, parsed.Format) {
+			formats = append(formats, parsed.Format)
+		}
+		result.MatchedLines += parsed.MatchedLines
+
+		for _, entry := range parsed.Entries {
+			if i, ok := index[entry.URL]; ok {
+				result.Entries[i].Sources = append(result.Entries[i].Sources, file)
+				continue
+			}
+			entry.Sources = []string{file}
+			index[entry.URL] = len(result.Entries)
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+
+	result.Format = strings.Join(formats, ", ")
+	return result, summaries, nil
+}
+
+// loadBatchInput fills the plan from the files of a batch request. The
+// request's InputFilePath then names the merged report: the directory when
+// one was given, otherwise "batch" next to the first file.
+func (p *scanPlan) loadBatchInput() error {
+	files, skipped, err := expandInputFiles(p.request)
+	if err != nil {
+		return err
+	}
+	p.response.SkippedFiles = skipped
+	parsed, summaries, err := parseInputFiles(files, p.inputOptions)
+	if err != nil {
+		return err
+	}
+
+	p.entries = parsed.Entries
+	p.response.InputFormat = parsed.Format
+	p.response.TotalMatchedLines = parsed.MatchedLines
+	p.response.InputFiles = summaries
+
+	paths := batchInputPaths(p.request)
+	if info, err := os.Stat(paths[0]); len(paths) == 1 && err == nil && info.IsDir() {
+		p.request.InputFilePath = filepath.Clean(paths[0])
+	} else {
+		p.request.InputFilePath = filepath.Join(filepath.Dir(files[0]), batchInputName)
+	}
+	return nil
+}
+
+// sourceResponse is the part of a batch response that came from one input
+// file, for a report of its own. Rows found in several files appear in each
+// of their reports.
+func sourceResponse(response ScanResponse, source InputFileSummary) ScanResponse {
+	part := response
+	part.InputFiles, part.SkippedFiles = nil, nil
+	part.InputFormat = source.Format
+	part.TotalMatchedLines = source.MatchedLines
+	part.TotalURLs = source.URLs
+	part.Succeeded, part.Failed, part.WildcardMatches, part.Replayed = 0, 0, 0, 0
+
+	part.Rows = nil
+	for _, row := range response.Rows {
+		if !slices.Contains(row.SourceFiles, source.Path) {
+			continue
+		}
+		part.Rows = append(part.Rows, row)
+		if row.Error == "" {
+			part.Succeeded++
+		} else {
+			part.Failed++
+		}
+		if row.WildcardMatch {
+			part.WildcardMatches++
+		}
+	}
+	part.SANPivots = collectSANPivots(part.Rows)
+	return part
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeBatchFile(t *testing.T, path, content string) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("create dir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	return path
+}
+
+func TestExpandInputFilesReadsDirectories(t *testing.T) {
+	dir := t.TempDir()
+	a := writeBatchFile(t, filepath.Join(dir, "a.txt"), "200 http://a/\n")
+	b := writeBatchFile(t, filepath.Join(dir, "b.log"), "200 http://b/\n")
+	nested := writeBatchFile(t, filepath.Join(dir, "sub", "c.txt"), "200 http://c/\n")
+	writeBatchFile(t, filepath.Join(dir, "notes.bin"), "200 http://d/\n")
+	report := writeBatchFile(t, filepath.Join(dir, "a_report.md"), "| http://a/ |\n")
+	export := writeBatchFile(t, filepath.Join(dir, "b_report.json"), "{}\n")
+	extra := writeBatchFile(t, filepath.Join(t.TempDir(), "extra.txt"), "200 http://e/\n")
+
+	cases := []struct {
+		name    string
+		request ScanRequest
+		want    []string
+		skipped []string
+	}{
+		{"directory", ScanRequest{InputFilePath: dir}, []string{a, b}, []string{report, export}},
+		{"recursive", ScanRequest{InputFilePath: dir, RecursiveInput: true}, []string{a, b, nested}, []string{report, export}},
+		{"glob", ScanRequest{InputFilePath: dir, InputPattern: "*.txt", RecursiveInput: true}, []string{a, nested}, nil},
+		{"files and directories", ScanRequest{InputFilePath: extra, InputFilePaths: []string{dir, a}}, []string{extra, a, b}, []string{report, export}},
+		{"report given as a file", ScanRequest{InputFilePaths: []string{a, report}}, []string{a, report}, nil},
+	}
+	for _, tc := range cases {
+		got, skipped, err := expandInputFiles(tc.request)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !reflect.DeepEqual(got, tc.want) || !reflect.DeepEqual(skipped, tc.skipped) {
+			t.Fatalf("%s: expected %v skipping %v, got %v skipping %v", tc.name, tc.want, tc.skipped, got, skipped)
+		}
+	}
+
+	for _, request := range []ScanRequest{
+		{InputFilePath: dir, InputPattern: "*.csv"},
+		{InputFilePath: dir, InputPattern: "[txt"},
+		{InputFilePaths: []string{filepath.Join(dir, "missing.txt")}},
+	} {
+		if _, _, err := expandInputFiles(request); err == nil {
+			t.Fatalf("expected an error for %+v", request)
+		}
+	}
+}
+
+func TestExpandInputFilesKeepsInputsNamedLikeReports(t *testing.T) {
+	dir := t.TempDir()
+	target := writeBatchFile(t, filepath.Join(dir, "target_report.txt"), "200 http://a/\n")
+	hits := writeBatchFile(t, filepath.Join(dir, "hits.log"), "200 http://b/\n")
+	merged := writeBatchFile(t, filepath.Join(dir, filepath.Base(dir)+"_report.md"), "| http://a/ |\n")
+	batch := writeBatchFile(t, filepath.Join(dir, "batch_report.csv"), "url\n")
+
+	got, skipped, err := expandInputFiles(ScanRequest{InputFilePath: dir})
+	if err != nil {
+		t.Fatalf("expand input files: %v", err)
+	}
+	if want := []string{hits, target}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if want := []string{merged, batch}; !slices.Equal(slices.Sorted(slices.Values(skipped)), slices.Sorted(slices.Values(want))) {
+		t.Fatalf("expected the generated reports %v to be skipped, got %v", want, skipped)
+	}
+}
+
+func TestParseInputFilesMergesDuplicateURLs(t *testing.T) {
+	dir := t.TempDir()
+	first := writeBatchFile(t, filepath.Join(dir, "first.txt"), "200 10B http://example.com/a\n403 http://example.com/b\n")
+	second := writeBatchFile(t, filepath.Join(dir, "second.txt"), "301 http://example.com/b\n200 http://example.com/c\n")
+
+	result, summaries, err := parseInputFiles([]string{first, second}, inputOptions{Filter: defaultStatusFilter()})
+	if err != nil {
+		t.Fatalf("parse input files: %v", err)
+	}
+
+	want := []inputEntry{
+		{URL: "http://example.com/a", Status: 200, Size: 10, Sources: []string{first}},
+		{URL: "http://example.com/b", Status: 403, Size: -1, Sources: []string{first, second}},
+		{URL: "http://example.com/c", Status: 200, Size: -1, Sources: []string{second}},
+	}
+	if !reflect.DeepEqual(result.Entries, want) {
+		t.Fatalf("expected %+v, got %+v", want, result.Entries)
+	}
+	if result.MatchedLines != 4 || len(summaries) != 2 || summaries[1].URLs != 2 || summaries[1].Path != second {
+		t.Fatalf("unexpected statistics %d %+v", result.MatchedLines, summaries)
+	}
+}
+
+func TestRunScanBatchWritesMergedOrPerFileReports(t *testing.T) {
+	server := newCLITestServer(t)
+	dir := t.TempDir()
+	first := writeBatchFile(t, filepath.Join(dir, "first.txt"), "200 "+server.URL+"/a\n200 "+server.URL+"/shared\n")
+	second := writeBatchFile(t, filepath.Join(dir, "second.txt"), "200 "+server.URL+"/shared\n")
+
+	app := NewApp()
+	response, err := app.RunScan(ScanRequest{InputFilePath: first, InputFilePaths: []string{second}, DisableFavicon: true})
+	if err != nil {
+		t.Fatalf("run batch scan: %v", err)
+	}
+	if response.TotalURLs != 2 || len(response.InputFiles) != 2 || response.ReportPath != filepath.Join(dir, "batch_report.md") {
+		t.Fatalf("unexpected merged response %+v", response)
+	}
+	if shared := response.Rows[1]; !reflect.DeepEqual(shared.SourceFiles, []string{first, second}) {
+		t.Fatalf("expected the shared URL to remember both files, got %+v", shared.SourceFiles)
+	}
+	report, err := os.ReadFile(response.ReportPath)
+	if err != nil || !strings.Contains(string(report), "### Input Files") || !strings.Contains(string(report), "[first.txt, second.txt]") {
+		t.Fatalf("expected the merged report to name the source files, got %v %s", err, report)
+	}
+
+	outputDir := t.TempDir()
+	response, err = app.RunScan(ScanRequest{InputFilePath: dir, OutputDir: outputDir, ReportPerSource: true, DisableFavicon: true})
+	if err != nil {
+		t.Fatalf("run per-file scan: %v", err)
+	}
+	want := []string{filepath.Join(outputDir, "first_report.md"), filepath.Join(outputDir, "second_report.md")}
+	if !reflect.DeepEqual(response.ReportPaths, want) {
+		t.Fatalf("expected one report per file, got %v", response.ReportPaths)
+	}
+	report, err = os.ReadFile(want[1])
+	if err != nil || strings.Contains(string(report), "/a ") || !strings.Contains(string(report), server.URL+"/shared") {
+		t.Fatalf("expected only the second file's URLs, got %v %s", err, report)
+	}
+
+	if _, err := app.RunScan(ScanRequest{InputFilePath: dir, Mode: scanModeDiscover, WordlistPath: first}); err == nil {
+		t.Fatal("expected discover mode to reject a directory of targets")
+	}
+}
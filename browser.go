@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	defaultBrowserProfile = "chrome-windows"
+	rotateBrowserProfiles = "rotate"
+)
+
+// browserProfile is the header set a real browser sends on a top-level
+// navigation. WAFs compare the User-Agent with its companion headers, so they
+// are kept together and in browser order.
+type browserProfile struct {
+	name    string
+	headers []headerField
+}
+
+var chromiumNavigationHeaders = []headerField{
+	{name: "Accept", value: "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7"},
+	{name: "Accept-Language", value: "zh-CN,zh;q=0.9,en;q=0.8"},
+	{name: "Upgrade-Insecure-Requests", value: "1"},
+	{name: "Sec-Fetch-Site", value: "none"},
+	{name: "Sec-Fetch-Mode", value: "navigate"},
+	{name: "Sec-Fetch-User", value: "?1"},
+	{name: "Sec-Fetch-Dest", value: "document"},
+}
+
+func chromiumProfile(name, userAgent, brands, platform string) browserProfile {
+	headers := []headerField{
+		{name: "User-Agent", value: userAgent},
+		{name: "Sec-Ch-Ua", value: brands},
+		{name: "Sec-Ch-Ua-Mobile", value: "?0"},
+		{name: "Sec-Ch-Ua-Platform", value: platform},
+	}
+	return browserProfile{name: name, headers: append(headers, chromiumNavigationHeaders...)}
+}
+
+func firefoxProfile(name, userAgent string) browserProfile {
+	return browserProfile{name: name, headers: []headerField{
+		{name: "User-Agent", value: userAgent},
+		{name: "Accept", value: "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"},
+		{name: "Accept-Language", value: "zh-CN,zh;q=0.8,zh-TW;q=0.7,zh-HK;q=0.5,en-US;q=0.3,en;q=0.2"},
+		{name: "Upgrade-Insecure-Requests", value: "1"},
+		{name: "Sec-Fetch-Dest", value: "document"},
+		{name: "Sec-Fetch-Mode", value: "navigate"},
+		{name: "Sec-Fetch-Site", value: "none"},
+		{name: "Sec-Fetch-User", value: "?1"},
+	}}
+}
+
+var browserProfiles = []browserProfile{
+	chromiumProfile(defaultBrowserProfile, defaultUserAgent,
+		`"Chromium";v="122", "Not(A:Brand";v="24", "Google Chrome";v="122"`, `"Windows"`),
+	chromiumProfile("chrome-macos",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+		`"Chromium";v="122", "Not(A:Brand";v="24", "Google Chrome";v="122"`, `"macOS"`),
+	chromiumProfile("edge-windows",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36 Edg/122.0.0.0",
+		`"Chromium";v="122", "Not(A:Brand";v="24", "Microsoft Edge";v="122"`, `"Windows"`),
+	firefoxProfile("firefox-windows", "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:123.0) Gecko/20100101 Firefox/123.0"),
+	firefoxProfile("firefox-linux", "Mozilla/5.0 (X11; Linux x86_64; rv:123.0) Gecko/20100101 Firefox/123.0"),
+	{name: "safari-macos", headers: []headerField{
+		{name: "User-Agent", value: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.3 Safari/605.1.15"},
+		{name: "Accept", value: "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"},
+		{name: "Accept-Language", value: "zh-CN,zh-Hans;q=0.9"},
+		{name: "Sec-Fetch-Site", value: "none"},
+		{name: "Sec-Fetch-Mode", value: "navigate"},
+		{name: "Sec-Fetch-Dest", value: "document"},
+	}},
+}
+
+func browserProfileNames() []string {
+	names := make([]string, 0, len(browserProfiles))
+	for _, profile := range browserProfiles {
+		names = append(names, profile.name)
+	}
+	return names
+}
+
+// browserProfileByName returns the named profile, or the default one for
+// unknown names.
+func browserProfileByName(name string) browserProfile {
+	for _, profile := range browserProfiles {
+		if profile.name == name {
+			return profile
+		}
+	}
+	return browserProfiles[0]
+}
+
+// browserPicker chooses the header set for each URL: a fixed profile, or the
+// whole pool in turn when rotating.
+type browserPicker struct {
+	profiles []browserProfile
+	next     *atomic.Uint64
+}
+
+func newBrowserPicker(name string) (browserPicker, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = defaultBrowserProfile
+	}
+	if name == rotateBrowserProfiles {
+		return browserPicker{profiles: browserProfiles, next: &atomic.Uint64{}}, nil
+	}
+
+	for _, profile := range browserProfiles {
+		if profile.name == name {
+			return browserPicker{profiles: []browserProfile{profile}, next: &atomic.Uint64{}}, nil
+		}
+	}
+	return browserPicker{}, fmt.Errorf("unknown browser profile %q", name)
+}
+
+func (p browserPicker) pick() browserProfile {
+	if len(p.profiles) == 0 {
+		return browserProfiles[0]
+	}
+	index := p.next.Add(1) - 1
+	return p.profiles[index%uint64(len(p.profiles))]
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRunScanWorkersRotatesBrowserProfiles(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		agents = make(map[string]string)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agents[r.URL.Path] = r.Header.Get("User-Agent")
+		mu.Unlock()
+		if r.Header.Get("Sec-Fetch-Mode") != "navigate" || r.Header.Get("Accept-Language") == "" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	entries := make([]inputEntry, 0, len(browserProfiles))
+	for _, name := range browserProfileNames() {
+		entries = append(entries, urlEntry(server.URL+"/"+name))
+	}
+
+	rows, err := runScanWorkers(context.Background(), entries, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		BrowserProfile: "rotate",
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	used := make(map[string]bool)
+	for _, row := range rows {
+		if row.StatusCode != http.StatusOK {
+			t.Fatalf("expected companion headers to be sent for %s, got %d", row.BrowserProfile, row.StatusCode)
+		}
+		used[row.BrowserProfile] = true
+	}
+	if len(used) != len(browserProfiles) {
+		t.Fatalf("expected every profile to be used once, got %v", used)
+	}
+
+	distinct := make(map[string]bool)
+	for _, agent := range agents {
+		distinct[agent] = true
+	}
+	if len(distinct) != len(browserProfiles) {
+		t.Fatalf("expected %d distinct user agents, got %d", len(browserProfiles), len(distinct))
+	}
+}
+
+func TestNewBrowserPickerSelectsProfile(t *testing.T) {
+	picker, err := newBrowserPicker("")
+	if err != nil || picker.pick().name != defaultBrowserProfile {
+		t.Fatalf("expected default profile %s, got %v", defaultBrowserProfile, err)
+	}
+
+	picker, err = newBrowserPicker("Firefox-Linux")
+	if err != nil {
+		t.Fatalf("newBrowserPicker returned error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if name := picker.pick().name; name != "firefox-linux" {
+			t.Fatalf("expected a fixed profile, got %s", name)
+		}
+	}
+
+	if _, err := newBrowserPicker("netscape"); err == nil {
+		t.Fatal("expected unknown profile to be rejected")
+	}
+}
+
+func TestAuthHeadersOverrideBrowserProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != "custom-agent" {
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL)}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		AuthRules:      []AuthRule{{Headers: "User-Agent: custom-agent"}},
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].StatusCode != http.StatusOK || rows[0].BrowserProfile != defaultBrowserProfile {
+		t.Fatalf("expected custom User-Agent to win, got %+v", rows)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// scanBudget caps the requests in flight across every job that shares it. A
+// limit of zero means no cap; a nil budget lets every request through.
+type scanBudget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+	wake  chan struct{}
+}
+
+func newScanBudget(limit int) *scanBudget {
+	return &scanBudget{limit: limit, wake: make(chan struct{})}
+}
+
+func (b *scanBudget) setLimit(limit int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.limit = limit
+	b.broadcast()
+}
+
+// broadcast wakes every waiting acquire. The caller holds mu.
+func (b *scanBudget) broadcast() {
+	close(b.wake)
+	b.wake = make(chan struct{})
+}
+
+// acquire blocks until a slot is free or ctx is cancelled.
+func (b *scanBudget) acquire(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		if b.limit <= 0 || b.used < b.limit {
+			b.used++
+			b.mu.Unlock()
+			return nil
+		}
+		wake := b.wake
+		b.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (b *scanBudget) release() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.used--
+	b.broadcast()
+}
+
+// scanGate is passed by a job's workers before every URL. It holds them while
+// the job is paused and then takes a slot from the shared budget; requests
+// already in flight when the job is paused still finish. A nil gate lets
+// every request through.
+type scanGate struct {
+	budget *scanBudget
+
+	mu      sync.Mutex
+	paused  bool
+	resumed chan struct{}
+}
+
+func newScanGate(budget *scanBudget) *scanGate {
+	return &scanGate{budget: budget}
+}
+
+func (g *scanGate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.paused {
+		g.paused = true
+		g.resumed = make(chan struct{})
+	}
+}
+
+func (g *scanGate) resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused {
+		g.paused = false
+		close(g.resumed)
+	}
+}
+
+func (g *scanGate) acquire(ctx context.Context) error {
+	if g == nil {
+		return nil
+	}
+	for {
+		g.mu.Lock()
+		paused, resumed := g.paused, g.resumed
+		g.mu.Unlock()
+		if paused {
+			select {
+			case <-resumed:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+
+		if err := g.budget.acquire(ctx); err != nil {
+			return err
+		}
+		// The job may have been paused while waiting for a slot.
+		g.mu.Lock()
+		paused = g.paused
+		g.mu.Unlock()
+		if !paused {
+			return nil
+		}
+		g.budget.release()
+	}
+}
+
+func (g *scanGate) release() {
+	if g != nil {
+		g.budget.release()
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScanBudgetCapsRequestsAcrossGates(t *testing.T) {
+	budget := newScanBudget(1)
+	first, second := newScanGate(budget), newScanGate(budget)
+
+	if err := first.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := second.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the second gate to wait for a slot, got %v", err)
+	}
+
+	first.release()
+	if err := second.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+
+	budget.setLimit(0)
+	if err := first.acquire(context.Background()); err != nil {
+		t.Fatalf("expected no cap after setLimit(0): %v", err)
+	}
+
+	var unlimited *scanGate
+	if err := unlimited.acquire(context.Background()); err != nil {
+		t.Fatalf("expected a nil gate to let requests through: %v", err)
+	}
+	unlimited.release()
+}
+
+func TestScanGateHoldsRequestsWhilePaused(t *testing.T) {
+	gate := newScanGate(newScanBudget(0))
+	gate.pause()
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- gate.acquire(context.Background())
+	}()
+
+	select {
+	case err := <-acquired:
+		t.Fatalf("expected acquire to wait while paused, got %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	gate.resume()
+	if err := <-acquired; err != nil {
+		t.Fatalf("acquire after resume: %v", err)
+	}
+
+	gate.pause()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := gate.acquire(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected cancellation to release a paused worker, got %v", err)
+	}
+}
@@ -0,0 +1,458 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Exit codes of the command line. Failed URLs are results, not errors: a scan
+// that ran to completion exits with exitOK however many of them failed.
+const (
+	exitOK          = 0
+	exitScanFailed  = 1
+	exitUsage       = 2
+	exitInterrupted = 130
+)
+
+const progressInterval = time.Second
+
+const (
+	stdinInputPath  = "-"
+	stdoutOutputDir = "-"
+)
+
+const cliUsage = `Usage:
+  handlerdirsearch                 start the desktop app
+  handlerdirsearch scan [options]  scan from the command line
+  handlerdirsearch - [options]     scan a report piped to stdin, same as scan -i -
+  handlerdirsearch serve [options] serve the scan API over HTTP
+
+Scan options:
+  -i, --input PATH           dirsearch/ffuf/... output, "-" for stdin, or target list in discover mode (required);
+                             repeat, or pass a directory, to scan several files as one batch
+      --glob PATTERNS        file names to read from input directories, e.g. "*.txt;*.log"
+                             (default: the text, log, md, csv, json, jsonl and xml files)
+  -r, --recursive            also read the subdirectories of input directories
+      --report-per-file      one report or export per input file instead of one merged report
+                             (not for jsonl or stdout, which stay merged)
+  -o, --output DIR           report directory, "-" for stdout (default: the input file's directory,
+                             stdout when reading stdin)
+  -f, --format FORMAT        md, json, csv or jsonl, one row per line as each URL finishes
+                             (default md, jsonl when reading stdin)
+  -c, --concurrency N        parallel requests (default 30, max 100)
+  -t, --timeout SECONDS      per-request timeout (default 5, max 120)
+      --mode MODE            import or discover (default import)
+  -w, --wordlist FILE        wordlist for discover mode
+  -e, --extensions LIST      extensions for %EXT% in discover mode, e.g. "php,asp"
+      --force-extensions     append extensions to every word
+      --base-url URL         base for relative paths in the input
+      --include-status SPEC  status codes to keep (default 200,301,403)
+      --exclude-status SPEC  status codes to drop
+      --exclude-sizes SPEC   response sizes to drop in discover mode
+      --no-follow-redirect   report redirects instead of following them
+      --wildcard             detect soft-404 / wildcard responses
+      --drop-wildcard        drop wildcard matches from the results
+      --rate N               requests per second across all hosts
+      --host-concurrency N   in-flight requests per host
+      --host-delay MS        delay between requests to one host
+      --host-jitter MS       random extra delay per request
+      --retries N            retries for failed requests
+      --retry-backoff MS     base retry backoff
+      --proxy URL            proxy; repeat to rotate through several
+      --replay-proxy URL     replay hits through this proxy after the scan
+      --auth-profile NAME    saved authentication profile
+      --browser NAME         browser header profile, or "rotate"
+  -k, --insecure             skip TLS certificate verification
+      --ca-bundle FILE       extra CA certificates (PEM)
+      --cert FILE            client certificate (PEM or PKCS#12)
+      --key FILE             client key (PEM)
+      --cert-password PASS   PKCS#12 password
+      --rules PATH           fingerprint rule file or directory; repeatable
+      --no-favicon           do not fetch favicons
+  -q, --quiet                no progress on stderr
+
+Serve options:
+  -l, --listen ADDR          address to listen on (default 127.0.0.1:8765)
+      --token TOKEN          bearer token for every request (default $HANDLERDIRSEARCH_TOKEN,
+                             otherwise a random token printed at startup)
+  -o, --output DIR           report directory (default ./handlerdirsearch-reports); a job's
+                             "outputDir" must be relative to it
+      --max-jobs N           jobs running at once, the rest wait in a queue (default 2)
+      --budget N             requests in flight across all running jobs (default 100)
+      --allow-server-paths   let jobs read files on the server (input, wordlist, rules,
+                             certificates) and use saved auth profiles
+
+API (Authorization: Bearer TOKEN):
+  POST /api/jobs                 submit a scan; the body is a scan request, with "input"
+                                 holding the report itself or, with --allow-server-paths,
+                                 "inputFilePath" a path on the server
+  GET  /api/jobs                 list jobs
+  GET  /api/jobs/{id}            job status and progress
+  GET  /api/jobs/{id}/rows       rows so far, ?offset=N&limit=N
+  GET  /api/jobs/{id}/report     report of a completed job, ?format=md|json|csv
+  POST /api/jobs/{id}/cancel     cancel a running job
+
+Exit codes: 0 finished, 1 scan error, 2 usage error, 130 interrupted.
+`
+
+// isCLICommand reports whether the first argument selects the command line
+// instead of the desktop app.
+func isCLICommand(arg string) bool {
+	switch arg {
+	case "scan", stdinInputPath, "serve", "help", "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+// runCLI runs one command and returns the process exit code. Cancelling ctx,
+// e.g. on Ctrl+C, stops a scan and still writes the partial results.
+func runCLI(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		io.WriteString(stderr, cliUsage)
+		return exitUsage
+	}
+
+	switch args[0] {
+	case "scan":
+		return runScanCommand(ctx, args[1:], stdin, stdout, stderr)
+	case stdinInputPath:
+		return runScanCommand(ctx, append([]string{"-i", stdinInputPath}, args[1:]...), stdin, stdout, stderr)
+	case "serve":
+		return runServeCommand(ctx, args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		io.WriteString(stdout, cliUsage)
+		return exitOK
+	}
+
+	fmt.Fprintf(stderr, "unknown command %q\n\n%s", args[0], cliUsage)
+	return exitUsage
+}
+
+func runScanCommand(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	request, format, quiet, err := parseScanFlags(args)
+	if errors.Is(err, flag.ErrHelp) {
+		io.WriteString(stdout, cliUsage)
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "%v\n\n%s", err, cliUsage)
+		return exitUsage
+	}
+
+	request = normalizeScanRequest(request)
+	var plan *scanPlan
+	if request.InputFilePath == stdinInputPath {
+		plan, err = prepareStreamScan(request, stdin)
+	} else {
+		plan, err = prepareScan(request)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	}
+	for _, path := range plan.response.SkippedFiles {
+		fmt.Fprintln(stderr, "skipped report of an earlier scan:", path)
+	}
+
+	var onProgress func(ScanProgress)
+	if !quiet {
+		onProgress = (&progressPrinter{w: stderr}).update
+	}
+
+	// JSON Lines are written as rows finish rather than after the scan.
+	var (
+		lines     *jsonLinesWriter
+		linesFile *os.File
+		onRow     func(ScanRowEvent)
+	)
+	if format == reportFormatJSONLines {
+		out := stdout
+		if !writesToStdout(plan.request) {
+			linesFile, err = createReportFile(plan.request, format)
+			if err != nil {
+				fmt.Fprintln(stderr, "error:", err)
+				return exitScanFailed
+			}
+			out = linesFile
+		}
+		lines = newJSONLinesWriter(out)
+		onRow = lines.write
+	}
+
+	response, err := plan.run(ctx, onRow, onProgress)
+	if linesFile != nil {
+		if closeErr := linesFile.Close(); lines.err == nil && closeErr != nil {
+			lines.err = fmt.Errorf("write report file: %w", closeErr)
+		}
+		response.ReportPath = linesFile.Name()
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	}
+
+	if lines != nil {
+		err = lines.err
+	} else {
+		err = writeScanResults(plan.request, &response, format, stdout)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	}
+
+	if !quiet {
+		fmt.Fprintf(stderr, "scanned %d of %d URLs: %d succeeded, %d failed\n",
+			response.Succeeded+response.Failed, response.TotalURLs, response.Succeeded, response.Failed)
+		if len(response.ReportPaths) > 0 {
+			for _, reportPath := range response.ReportPaths {
+				fmt.Fprintln(stderr, "report:", reportPath)
+			}
+		} else if response.ReportPath != "" {
+			fmt.Fprintln(stderr, "report:", response.ReportPath)
+		}
+	}
+	if response.Cancelled {
+		fmt.Fprintln(stderr, "scan interrupted, results are partial")
+		return exitInterrupted
+	}
+	return exitOK
+}
+
+// parseScanFlags turns the scan command's arguments into a ScanRequest, the
+// report format and whether progress is suppressed.
+func parseScanFlags(args []string) (ScanRequest, string, bool, error) {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	var (
+		request          ScanRequest
+		format           string
+		quiet            bool
+		noFollowRedirect bool
+		inputs           listFlag
+		proxies          listFlag
+		rules            listFlag
+	)
+	flags.Var(&inputs, "i", "")
+	flags.Var(&inputs, "input", "")
+	stringFlag(flags, &request.InputPattern, "", "glob")
+	for _, name := range []string{"r", "recursive"} {
+		flags.BoolVar(&request.RecursiveInput, name, false, "")
+	}
+	flags.BoolVar(&request.ReportPerSource, "report-per-file", false, "")
+	stringFlag(flags, &request.OutputDir, "", "o", "output")
+	stringFlag(flags, &format, reportFormatMarkdown, "f", "format")
+	intFlag(flags, &request.Concurrency, defaultConcurrency, "c", "concurrency")
+	intFlag(flags, &request.TimeoutSeconds, defaultTimeoutSecond, "t", "timeout")
+	stringFlag(flags, &request.Mode, scanModeImport, "mode")
+	stringFlag(flags, &request.WordlistPath, "", "w", "wordlist")
+	stringFlag(flags, &request.Extensions, "", "e", "extensions")
+	flags.BoolVar(&request.ForceExtensions, "force-extensions", false, "")
+	stringFlag(flags, &request.BaseURL, "", "base-url")
+	stringFlag(flags, &request.IncludeStatus, "", "include-status")
+	stringFlag(flags, &request.ExcludeStatus, "", "exclude-status")
+	stringFlag(flags, &request.ExcludeSizes, "", "exclude-sizes")
+	flags.BoolVar(&noFollowRedirect, "no-follow-redirect", false, "")
+	flags.BoolVar(&request.DetectWildcard, "wildcard", false, "")
+	flags.BoolVar(&request.DropWildcard, "drop-wildcard", false, "")
+	intFlag(flags, &request.RequestsPerSecond, 0, "rate")
+	intFlag(flags, &request.PerHostConcurrency, 0, "host-concurrency")
+	intFlag(flags, &request.PerHostDelayMs, 0, "host-delay")
+	intFlag(flags, &request.PerHostJitterMs, 0, "host-jitter")
+	intFlag(flags, &request.MaxRetries, 0, "retries")
+	intFlag(flags, &request.RetryBackoffMs, defaultRetryBackoffMs, "retry-backoff")
+	flags.Var(&proxies, "proxy", "")
+	stringFlag(flags, &request.ReplayProxyURL, "", "replay-proxy")
+	stringFlag(flags, &request.AuthProfile, "", "auth-profile")
+	stringFlag(flags, &request.BrowserProfile, "", "browser")
+	for _, name := range []string{"k", "insecure"} {
+		flags.BoolVar(&request.InsecureSkipVerify, name, false, "")
+	}
+	stringFlag(flags, &request.CABundlePath, "", "ca-bundle")
+	stringFlag(flags, &request.ClientCertPath, "", "cert")
+	stringFlag(flags, &request.ClientKeyPath, "", "key")
+	stringFlag(flags, &request.ClientCertPassword, "", "cert-password")
+	flags.Var(&rules, "rules", "")
+	flags.BoolVar(&request.DisableFavicon, "no-favicon", false, "")
+	for _, name := range []string{"q", "quiet"} {
+		flags.BoolVar(&quiet, name, false, "")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return ScanRequest{}, "", false, err
+	}
+	if flags.NArg() > 0 {
+		return ScanRequest{}, "", false, fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+	if len(inputs) == 0 || strings.TrimSpace(inputs[0]) == "" {
+		return ScanRequest{}, "", false, errors.New("missing -i/--input")
+	}
+	request.InputFilePath = inputs[0]
+	if len(inputs) > 1 {
+		if slices.Contains(inputs, stdinInputPath) {
+			return ScanRequest{}, "", false, errors.New("stdin cannot be combined with other inputs")
+		}
+		request.InputFilePaths = inputs[1:]
+	}
+
+	if request.InputFilePath == stdinInputPath {
+		set := make(map[string]bool)
+		flags.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if !set["f"] && !set["format"] {
+			format = reportFormatJSONLines
+		}
+		if !set["o"] && !set["output"] {
+			request.OutputDir = stdoutOutputDir
+		}
+	}
+
+	format = strings.ToLower(strings.TrimSpace(format))
+	switch format {
+	case reportFormatMarkdown, reportFormatJSON, reportFormatCSV, reportFormatJSONLines:
+	default:
+		return ScanRequest{}, "", false, fmt.Errorf("unknown format %q, want md, json, csv or jsonl", format)
+	}
+
+	request.FollowRedirect = !noFollowRedirect
+	request.ProxyList = strings.Join(proxies, "\n")
+	request.FingerprintRulePaths = strings.Join(rules, "\n")
+	return request, format, quiet, nil
+}
+
+// writeScanResults writes the response in format to the report directory, or
+// to stdout when the output is "-". Markdown reports are appended to like the
+// desktop app's; JSON and CSV exports are replaced. With ReportPerSource a
+// batch scan gets one report or export per input file, except on stdout.
+func writeScanResults(request ScanRequest, response *ScanResponse, format string, stdout io.Writer) error {
+	if writesToStdout(request) {
+		return writeReport(stdout, request, *response, format)
+	}
+
+	if format == reportFormatMarkdown {
+		if err := os.MkdirAll(reportDir(request), 0o755); err != nil {
+			return fmt.Errorf("create output directory: %w", err)
+		}
+		return finishScan(request, response)
+	}
+
+	response.ReportPaths = nil
+	if request.ReportPerSource && len(response.InputFiles) > 0 {
+		for _, source := range response.InputFiles {
+			sourceRequest := request
+			sourceRequest.InputFilePath = source.Path
+			reportPath, err := writeReportFile(sourceRequest, sourceResponse(*response, source), format)
+			if err != nil {
+				return err
+			}
+			response.ReportPaths = append(response.ReportPaths, reportPath)
+		}
+	} else {
+		reportPath, err := writeReportFile(request, *response, format)
+		if err != nil {
+			return err
+		}
+		response.ReportPaths = []string{reportPath}
+	}
+	response.ReportPath = response.ReportPaths[0]
+	return nil
+}
+
+// writeReportFile replaces the export named after the request's input and
+// returns its path.
+func writeReportFile(request ScanRequest, response ScanResponse, format string) (string, error) {
+	file, err := createReportFile(request, format)
+	if err != nil {
+		return "", err
+	}
+	if err := writeReport(file, request, response, format); err != nil {
+		file.Close()
+		return "", fmt.Errorf("write report file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return "", fmt.Errorf("write report file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+func writesToStdout(request ScanRequest) bool {
+	return strings.TrimSpace(request.OutputDir) == stdoutOutputDir
+}
+
+// createReportFile creates, or truncates, the export named after the input in
+// the report directory.
+func createReportFile(request ScanRequest, format string) (*os.File, error) {
+	if err := os.MkdirAll(reportDir(request), 0o755); err != nil {
+		return nil, fmt.Errorf("create output directory: %w", err)
+	}
+
+	file, err := os.Create(filepath.Join(reportDir(request), exportFileName(request.InputFilePath, format)))
+	if err != nil {
+		return nil, fmt.Errorf("create report file: %w", err)
+	}
+	return file, nil
+}
+
+func writeReport(w io.Writer, request ScanRequest, response ScanResponse, format string) error {
+	switch format {
+	case reportFormatJSON:
+		return writeJSONReport(w, response)
+	case reportFormatCSV:
+		return writeCSVReport(w, response.Rows)
+	default:
+		_, err := io.WriteString(w, buildMarkdownReport(request.InputFilePath, response))
+		return err
+	}
+}
+
+// progressPrinter writes a progress line at most once per progressInterval,
+// and always for the last URL.
+type progressPrinter struct {
+	w    io.Writer
+	last time.Time
+}
+
+func (p *progressPrinter) update(progress ScanProgress) {
+	now := time.Now()
+	if progress.Done < progress.Total && now.Sub(p.last) < progressInterval {
+		return
+	}
+	p.last = now
+
+	fmt.Fprintf(p.w, "[%d/%d] %d succeeded, %d failed, %.1f req/s, ETA %s\n",
+		progress.Done, progress.Total, progress.Succeeded, progress.Failed,
+		progress.RatePerSecond, time.Duration(progress.ETASeconds)*time.Second)
+}
+
+// listFlag collects every value of a repeatable flag.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+func stringFlag(flags *flag.FlagSet, target *string, value string, names ...string) {
+	for _, name := range names {
+		flags.StringVar(target, name, value, "")
+	}
+}
+
+func intFlag(flags *flag.FlagSet, target *int, value int, names ...string) {
+	for _, name := range names {
+		flags.IntVar(target, name, value, "")
+	}
+}
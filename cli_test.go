@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeCLIInput(t *testing.T, lines ...string) string {
+	t.Helper()
+	inputPath := filepath.Join(t.TempDir(), "hits.txt")
+	if err := os.WriteFile(inputPath, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("write input: %v", err)
+	}
+	return inputPath
+}
+
+func newCLITestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("<html><head><title>CLI</title></head><body>ok</body></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRunCLIScanWritesJSONToStdout(t *testing.T) {
+	server := newCLITestServer(t)
+	inputPath := writeCLIInput(t, "200 10B "+server.URL+"/home", "404 10B "+server.URL+"/missing")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"scan", "-i", inputPath, "-o", "-", "--format", "json", "-c", "2", "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+
+	var response ScanResponse
+	if err := json.Unmarshal(stdout.Bytes(), &response); err != nil {
+		t.Fatalf("expected JSON on stdout: %v\n%s", err, stdout.String())
+	}
+	if response.TotalURLs != 1 || len(response.Rows) != 1 || response.Rows[0].Title != "CLI" {
+		t.Fatalf("expected the one filtered URL, got %+v", response)
+	}
+	if !strings.Contains(stderr.String(), "[1/1]") || !strings.Contains(stderr.String(), "1 succeeded") {
+		t.Fatalf("expected progress on stderr, got %q", stderr.String())
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(inputPath), "hits_report.md")); !os.IsNotExist(err) {
+		t.Fatalf("expected no report file when writing to stdout, got %v", err)
+	}
+}
+
+func TestRunCLIScanWritesReportFiles(t *testing.T) {
+	server := newCLITestServer(t)
+	inputPath := writeCLIInput(t, "200 10B "+server.URL+"/home")
+	outputDir := filepath.Join(t.TempDir(), "reports", "nested")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"scan", "-i", inputPath, "-o", outputDir, "-f", "csv", "-q", "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if stdout.Len() != 0 || stderr.Len() != 0 {
+		t.Fatalf("expected a quiet run, got stdout %q stderr %q", stdout.String(), stderr.String())
+	}
+
+	file, err := os.Open(filepath.Join(outputDir, "hits_report.csv"))
+	if err != nil {
+		t.Fatalf("open csv report: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("read csv report: %v", err)
+	}
+	if len(records) != 2 || records[0][0] != "url" || records[1][0] != server.URL+"/home" || records[1][3] != "CLI" {
+		t.Fatalf("unexpected csv report %v", records)
+	}
+
+	code = runCLI(context.Background(), []string{"scan", "--input", inputPath, "--output", outputDir, "-q", "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	report, err := os.ReadFile(filepath.Join(outputDir, "hits_report.md"))
+	if err != nil || !strings.Contains(string(report), server.URL+"/home") {
+		t.Fatalf("expected the Markdown report by default, got %v %s", err, report)
+	}
+}
+
+func TestRunCLIStreamsStdinAsJSONLines(t *testing.T) {
+	server := newCLITestServer(t)
+	stdin := strings.NewReader("200 " + server.URL + "/a\n403 " + server.URL + "/b\n404 " + server.URL + "/missing\n")
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"-", "-c", "1", "--no-favicon"}, stdin, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one JSON line per scanned URL, got %q", stdout.String())
+	}
+	for i, path := range []string{"/a", "/b"} {
+		var row ScanRow
+		if err := json.Unmarshal([]byte(lines[i]), &row); err != nil {
+			t.Fatalf("line %d is not a JSON row: %v", i, err)
+		}
+		if row.URL != server.URL+path || row.Title != "CLI" {
+			t.Fatalf("unexpected row %+v", row)
+		}
+	}
+	if !strings.Contains(stderr.String(), "scanned 2 of 2 URLs") {
+		t.Fatalf("expected the summary on stderr, got %q", stderr.String())
+	}
+
+	outputDir := t.TempDir()
+	stdin = strings.NewReader("200 " + server.URL + "/a\n")
+	code = runCLI(context.Background(), []string{"scan", "-i", "-", "-o", outputDir, "-q", "--no-favicon"}, stdin, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, "stdin_report.jsonl")); err != nil || strings.Count(string(data), "\n") != 1 {
+		t.Fatalf("expected the JSON Lines file named after stdin, got %v %q", err, data)
+	}
+}
+
+func TestRunCLIScansSeveralInputFiles(t *testing.T) {
+	server := newCLITestServer(t)
+	first := writeCLIInput(t, "200 "+server.URL+"/a")
+	second := writeCLIInput(t, "200 "+server.URL+"/a", "200 "+server.URL+"/b")
+	outputDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"scan", "-i", first, "--input", second, "-o", outputDir, "-f", "csv", "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+
+	file, err := os.Open(filepath.Join(outputDir, "batch_report.csv"))
+	if err != nil {
+		t.Fatalf("open merged csv report: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("expected the two distinct URLs, got %v %v", err, records)
+	}
+	column := len(records[0]) - 1
+	if records[0][column] != "source_files" || records[1][column] != first+"; "+second || records[2][column] != second {
+		t.Fatalf("expected each URL's source files, got %v", records)
+	}
+}
+
+func TestRunCLIExportsOneFilePerInput(t *testing.T) {
+	server := newCLITestServer(t)
+	dir := t.TempDir()
+	writeBatchFile(t, filepath.Join(dir, "first.txt"), "200 "+server.URL+"/a\n")
+	writeBatchFile(t, filepath.Join(dir, "second.txt"), "200 "+server.URL+"/b\n200 "+server.URL+"/c\n")
+	earlier := writeBatchFile(t, filepath.Join(dir, "first_report.csv"), "url\n")
+	outputDir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(context.Background(), []string{"scan", "-i", dir, "-o", outputDir, "-f", "csv", "--report-per-file", "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitOK {
+		t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "skipped report of an earlier scan: "+earlier) {
+		t.Fatalf("expected the skipped report to be logged, got %q", stderr.String())
+	}
+
+	for name, rows := range map[string]int{"first_report.csv": 1, "second_report.csv": 2} {
+		file, err := os.Open(filepath.Join(outputDir, name))
+		if err != nil {
+			t.Fatalf("open %s: %v", name, err)
+		}
+		records, err := csv.NewReader(file).ReadAll()
+		file.Close()
+		if err != nil || len(records) != rows+1 {
+			t.Fatalf("expected %d rows in %s, got %v %v", rows, name, err, records)
+		}
+	}
+}
+
+func TestRunCLIExitCodes(t *testing.T) {
+	inputPath := writeCLIInput(t, "200 10B http://127.0.0.1:1/")
+
+	cases := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"no command", nil, exitUsage},
+		{"unknown command", []string{"crawl"}, exitUsage},
+		{"help", []string{"help"}, exitOK},
+		{"scan help", []string{"scan", "-h"}, exitOK},
+		{"missing input flag", []string{"scan"}, exitUsage},
+		{"unknown flag", []string{"scan", "-i", inputPath, "--bogus"}, exitUsage},
+		{"bad format", []string{"scan", "-i", inputPath, "--format", "xml"}, exitUsage},
+		{"extra argument", []string{"scan", "-i", inputPath, "extra"}, exitUsage},
+		{"missing input file", []string{"scan", "-i", inputPath + ".missing"}, exitScanFailed},
+		{"invalid setting", []string{"scan", "-i", inputPath, "--proxy", "ftp://proxy"}, exitScanFailed},
+		{"discover from stdin", []string{"-", "--mode", "discover", "-w", inputPath}, exitScanFailed},
+		{"stdin in a batch", []string{"scan", "-i", inputPath, "-i", "-"}, exitUsage},
+		{"bad input glob", []string{"scan", "-i", filepath.Dir(inputPath), "--glob", "[txt"}, exitScanFailed},
+	}
+	for _, tc := range cases {
+		var stdout, stderr bytes.Buffer
+		if code := runCLI(context.Background(), tc.args, strings.NewReader(""), &stdout, &stderr); code != tc.code {
+			t.Fatalf("%s: expected exit code %d, got %d: %s", tc.name, tc.code, code, stderr.String())
+		}
+	}
+}
+
+func TestRunCLIInterruptedScanWritesPartialReport(t *testing.T) {
+	server := newCLITestServer(t)
+	inputPath := writeCLIInput(t, "200 10B "+server.URL+"/home")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var stdout, stderr bytes.Buffer
+	code := runCLI(ctx, []string{"scan", "-i", inputPath, "--no-favicon"}, nil, &stdout, &stderr)
+	if code != exitInterrupted {
+		t.Fatalf("expected exit code %d, got %d: %s", exitInterrupted, code, stderr.String())
+	}
+
+	report, err := os.ReadFile(filepath.Join(filepath.Dir(inputPath), "hits_report.md"))
+	if err != nil || !strings.Contains(string(report), "Cancelled") {
+		t.Fatalf("expected a cancelled report, got %v %s", err, report)
+	}
+}
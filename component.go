@@ -0,0 +1,160 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+const (
+	fullConfidence    = 100
+	maxEvidenceLength = 80
+)
+
+var (
+	// "WordPress 6.4.2", "Drupal 10 (https://www.drupal.org)", "Hugo v0.120"
+	generatorVersionRegex = regexp.MustCompile(`^(.+?)[\s/]+v?(\d+(?:\.\d+)*[\w.-]*)`)
+	versionNumberRegex    = regexp.MustCompile(`^v?\d+(?:\.\d+)*$`)
+)
+
+// Component is a technology identified on a page. Confidence is a
+// percentage; Evidence is the snippet of the response that gave it away.
+type Component struct {
+	Name       string `json:"name"`
+	Version    string `json:"version"`
+	Category   string `json:"category"`
+	Confidence int    `json:"confidence"`
+	Evidence   string `json:"evidence"`
+}
+
+// label is the flat form kept in ScanRow.Components, e.g. "WordPress 6.4".
+func (c Component) label() string {
+	if c.Version == "" {
+		return c.Name
+	}
+	return c.Name + " " + c.Version
+}
+
+// String is the report form, e.g. "WordPress 6.4 (90%)".
+func (c Component) String() string {
+	return fmt.Sprintf("%s (%d%%)", c.label(), c.Confidence)
+}
+
+// headerComponents reads the products named by technology-revealing headers
+// and the meta generator, e.g. "Server: Apache/2.4.41 (Ubuntu) OpenSSL/1.1.1"
+// yields Apache 2.4.41 and OpenSSL 1.1.1. Products recognised by fingerprint
+// rules are added later by identifyComponents.
+func headerComponents(resp *http.Response, generator string) []Component {
+	components := make([]Component, 0)
+	for _, key := range []string{"Server", "X-Powered-By", "Via"} {
+		for _, value := range resp.Header.Values(key) {
+			evidence := truncateEvidence(key + ": " + value)
+			for _, product := range parseProductTokens(value) {
+				product.Category = headerCategory(key)
+				product.Evidence = evidence
+				components = mergeComponent(components, product)
+			}
+		}
+	}
+
+	for _, header := range [][2]string{{"X-AspNet-Version", "ASP.NET"}, {"X-AspNetMvc-Version", "ASP.NET MVC"}} {
+		if value := strings.TrimSpace(resp.Header.Get(header[0])); value != "" {
+			components = mergeComponent(components, Component{
+				Name:       header[1],
+				Version:    value,
+				Category:   "Web frameworks",
+				Confidence: fullConfidence,
+				Evidence:   truncateEvidence(header[0] + ": " + value),
+			})
+		}
+	}
+
+	if generator != "" {
+		component := Component{Name: generator, Confidence: fullConfidence, Evidence: truncateEvidence("meta generator: " + generator)}
+		if match := generatorVersionRegex.FindStringSubmatch(generator); match != nil {
+			component.Name = strings.TrimSpace(match[1])
+			component.Version = match[2]
+		}
+		components = mergeComponent(components, component)
+	}
+	return components
+}
+
+// parseProductTokens splits a header value into "product/version" tokens,
+// including those inside comments. Bare version numbers, such as the
+// protocol version of a Via hop, are skipped.
+func parseProductTokens(value string) []Component {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return strings.ContainsRune(" \t,;()", r)
+	})
+
+	products := make([]Component, 0, len(fields))
+	for _, field := range fields {
+		name, version, _ := strings.Cut(field, "/")
+		if name == "" || versionNumberRegex.MatchString(name) {
+			continue
+		}
+		products = append(products, Component{Name: name, Version: strings.TrimPrefix(version, "v"), Confidence: fullConfidence})
+	}
+	return products
+}
+
+func headerCategory(key string) string {
+	switch key {
+	case "Server":
+		return "Web servers"
+	case "Via":
+		return "Reverse proxies"
+	default:
+		return ""
+	}
+}
+
+// mergeComponent adds component to components, or completes the entry of
+// the same name with the version, category and evidence it lacks and the
+// higher confidence.
+func mergeComponent(components []Component, component Component) []Component {
+	for i := range components {
+		existing := &components[i]
+		if !strings.EqualFold(existing.Name, component.Name) {
+			continue
+		}
+		if existing.Version == "" {
+			existing.Version = component.Version
+		}
+		if existing.Category == "" {
+			existing.Category = component.Category
+		}
+		if existing.Evidence == "" {
+			existing.Evidence = component.Evidence
+		}
+		if component.Confidence > existing.Confidence {
+			existing.Confidence = component.Confidence
+		}
+		return components
+	}
+	return append(components, component)
+}
+
+// componentLabels is the flat view of components, with the "N/A"
+// placeholder for pages without any.
+func componentLabels(components []Component) []string {
+	if len(components) == 0 {
+		return []string{"N/A"}
+	}
+	labels := make([]string, 0, len(components))
+	for _, component := range components {
+		labels = append(labels, component.label())
+	}
+	return labels
+}
+
+func truncateEvidence(evidence string) string {
+	evidence = strings.Join(strings.Fields(evidence), " ")
+	runes := []rune(evidence)
+	if len(runes) <= maxEvidenceLength {
+		return evidence
+	}
+	return string(runes[:maxEvidenceLength-3]) + "..."
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestHeaderComponentsExtractsProductsAndVersions(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Server", "Apache/2.4.41 (Ubuntu) OpenSSL/1.1.1f")
+	headers.Set("X-Powered-By", "PHP/7.4.3")
+	headers.Set("Via", "1.1 varnish (Varnish/6.0)")
+	headers.Set("X-AspNet-Version", "4.0.30319")
+
+	components := headerComponents(&http.Response{Header: headers}, "Drupal 10 (https://www.drupal.org)")
+
+	got := componentLabels(components)
+	want := []string{"Apache 2.4.41", "Ubuntu", "OpenSSL 1.1.1f", "PHP 7.4.3", "varnish 6.0", "ASP.NET 4.0.30319", "Drupal 10"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if components[0].Category != "Web servers" || components[0].Evidence != "Server: Apache/2.4.41 (Ubuntu) OpenSSL/1.1.1f" || components[0].Confidence != 100 {
+		t.Fatalf("unexpected server component: %+v", components[0])
+	}
+	if drupal := components[len(components)-1]; drupal.Evidence != "meta generator: Drupal 10 (https://www.drupal.org)" {
+		t.Fatalf("unexpected generator evidence: %+v", drupal)
+	}
+}
+
+func TestHeaderComponentsKeepsGeneratorWithoutVersion(t *testing.T) {
+	components := headerComponents(&http.Response{Header: http.Header{}}, "Joomla! - Open Source Content Management")
+	if len(components) != 1 || components[0].Name != "Joomla! - Open Source Content Management" || components[0].Version != "" {
+		t.Fatalf("expected the generator as name, got %+v", components)
+	}
+
+	if got := componentLabels(nil); !reflect.DeepEqual(got, []string{"N/A"}) {
+		t.Fatalf("expected the N/A placeholder, got %v", got)
+	}
+}
+
+func TestMergeComponentCompletesExistingEntry(t *testing.T) {
+	components := []Component{{Name: "PHP", Confidence: 50, Evidence: "body: <?php"}}
+	components = mergeComponent(components, Component{Name: "php", Version: "8.2", Category: "Programming languages", Confidence: 100, Evidence: "X-Powered-By: PHP/8.2"})
+
+	want := []Component{{Name: "PHP", Version: "8.2", Category: "Programming languages", Confidence: 100, Evidence: "body: <?php"}}
+	if !reflect.DeepEqual(components, want) {
+		t.Fatalf("expected %+v, got %+v", want, components)
+	}
+}
+
+func TestFormatComponentsShowsVersionAndConfidence(t *testing.T) {
+	row := ScanRow{ComponentDetails: []Component{
+		{Name: "WordPress", Version: "6.4", Confidence: 90},
+		{Name: "PHP", Confidence: 100},
+	}}
+	if got := formatComponents(row); got != "WordPress 6.4 (90%), PHP (100%)" {
+		t.Fatalf("unexpected components cell %q", got)
+	}
+
+	if got := formatComponents(ScanRow{Components: []string{"nginx"}}); got != "nginx" {
+		t.Fatalf("expected the flat view without details, got %q", got)
+	}
+	if got := formatComponents(ScanRow{}); got != "N/A" {
+		t.Fatalf("expected N/A, got %q", got)
+	}
+}
+
+func TestTruncateEvidence(t *testing.T) {
+	long := ""
+	for i := 0; i < 20; i++ {
+		long += "abcde\n"
+	}
+	got := truncateEvidence(long)
+	if len([]rune(got)) != maxEvidenceLength || got[len(got)-3:] != "..." {
+		t.Fatalf("expected evidence cut to %d runes, got %q", maxEvidenceLength, got)
+	}
+}
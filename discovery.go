@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"iter"
+	"os"
+	"strings"
+)
+
+const (
+	scanModeImport   = "import"
+	scanModeDiscover = "discover"
+
+	extensionPlaceholder = "%EXT%"
+)
+
+// sizeFilter drops responses whose body length falls into one of its
+// inclusive ranges, like dirsearch's --exclude-sizes.
+type sizeFilter struct {
+	exclude [][2]int64
+}
+
+// parseSizeFilter parses a comma separated list of sizes ("0", "12KB") and
+// ranges ("100-200").
+func parseSizeFilter(spec string) (sizeFilter, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	filter := sizeFilter{}
+	for _, field := range fields {
+		from, to, isRange := strings.Cut(field, "-")
+		if !isRange {
+			to = from
+		}
+
+		minSize := parseSize(from)
+		maxSize := parseSize(to)
+		if minSize < 0 || maxSize < 0 || minSize > maxSize {
+			return sizeFilter{}, fmt.Errorf("invalid size %q", field)
+		}
+		filter.exclude = append(filter.exclude, [2]int64{minSize, maxSize})
+	}
+
+	return filter, nil
+}
+
+func (f sizeFilter) Match(size int64) bool {
+	for _, excluded := range f.exclude {
+		if size >= excluded[0] && size <= excluded[1] {
+			return false
+		}
+	}
+	return true
+}
+
+// discoveryFilter decides which probed paths count as hits.
+type discoveryFilter struct {
+	status statusFilter
+	size   sizeFilter
+}
+
+func (f discoveryFilter) Match(row ScanRow) bool {
+	if row.StatusCode == 0 {
+		return false
+	}
+	return f.status.Match(row.StatusCode) && f.size.Match(row.ContentLength)
+}
+
+// readTargetsFile reads one base URL per line. Blank lines and lines starting
+// with "#" are skipped, and bare hosts default to http.
+func readTargetsFile(path string) ([]string, error) {
+	lines, err := readListFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read targets file: %w", err)
+	}
+
+	targets := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if !strings.Contains(line, "://") {
+			line = "http://" + line
+		}
+		targets = append(targets, line)
+	}
+	return targets, nil
+}
+
+func readWordlistFile(path string) ([]string, error) {
+	words, err := readListFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read wordlist: %w", err)
+	}
+	return words, nil
+}
+
+func readListFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 1024), 1024*1024)
+
+	lines := make([]string, 0)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.TrimPrefix(scanner.Text(), string(utf8BOM)))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// parseExtensions splits "php, .asp,aspx" into ["php", "asp", "aspx"].
+func parseExtensions(spec string) []string {
+	extensions := make([]string, 0)
+	for _, field := range strings.Split(spec, ",") {
+		extension := strings.TrimPrefix(strings.TrimSpace(field), ".")
+		if extension != "" {
+			extensions = append(extensions, extension)
+		}
+	}
+	return extensions
+}
+
+// expandWordlist applies dirsearch's extension rules: words containing %EXT%
+// are expanded once per extension (and dropped when there are none), and with
+// forceExtensions every other word is also tried with each extension appended.
+func expandWordlist(words, extensions []string, forceExtensions bool) []string {
+	paths := make([]string, 0, len(words))
+	seen := make(map[string]struct{})
+	add := func(path string) {
+		if _, ok := seen[path]; ok {
+			return
+		}
+		seen[path] = struct{}{}
+		paths = append(paths, path)
+	}
+
+	for _, word := range words {
+		if strings.Contains(word, extensionPlaceholder) {
+			for _, extension := range extensions {
+				add(strings.ReplaceAll(word, extensionPlaceholder, extension))
+			}
+			continue
+		}
+
+		add(word)
+		if !forceExtensions || strings.HasSuffix(word, "/") {
+			continue
+		}
+		for _, extension := range extensions {
+			add(word + "." + extension)
+		}
+	}
+
+	return paths
+}
+
+// discoveryCandidates is every target combined with every wordlist path. The
+// URLs are built as the scheduler queues them, and it only queues a bounded
+// number ahead of the workers, so a large wordlist over many targets is never
+// held in memory at once.
+type discoveryCandidates struct {
+	targets []string
+	paths   []string
+}
+
+func (c discoveryCandidates) count() int {
+	return len(c.targets) * len(c.paths)
+}
+
+// entries yields the candidate URLs target by target, in wordlist order.
+func (c discoveryCandidates) entries() iter.Seq[inputEntry] {
+	return func(yield func(inputEntry) bool) {
+		for _, target := range c.targets {
+			for _, path := range c.paths {
+				if !yield(urlEntry(joinTargetPath(target, path))) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// loadDiscoveryCandidates reads the targets list (the request's input file)
+// and the wordlist.
+func loadDiscoveryCandidates(request ScanRequest) (discoveryCandidates, error) {
+	targets, err := readTargetsFile(request.InputFilePath)
+	if err != nil {
+		return discoveryCandidates{}, err
+	}
+
+	words, err := readWordlistFile(request.WordlistPath)
+	if err != nil {
+		return discoveryCandidates{}, err
+	}
+
+	paths := expandWordlist(words, parseExtensions(request.Extensions), request.ForceExtensions)
+	return discoveryCandidates{targets: targets, paths: paths}, nil
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExpandWordlistAppliesExtensionRules(t *testing.T) {
+	words := []string{"admin", "index.%EXT%", "backup/", "login.%EXT%.bak", "admin"}
+
+	paths := expandWordlist(words, parseExtensions("php, .asp"), false)
+	expected := []string{"admin", "index.php", "index.asp", "backup/", "login.php.bak", "login.asp.bak"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, paths)
+	}
+
+	forced := expandWordlist(words, []string{"php"}, true)
+	expected = []string{"admin", "admin.php", "index.php", "backup/", "login.php.bak"}
+	if strings.Join(forced, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, forced)
+	}
+
+	if paths := expandWordlist([]string{"index.%EXT%"}, nil, false); len(paths) != 0 {
+		t.Fatalf("expected %%EXT%% words to be dropped without extensions, got %v", paths)
+	}
+}
+
+func TestParseSizeFilterExcludesSizesAndRanges(t *testing.T) {
+	filter, err := parseSizeFilter("0, 1KB, 200-300")
+	if err != nil {
+		t.Fatalf("parse size filter: %v", err)
+	}
+
+	for size, expected := range map[int64]bool{0: false, 1: true, 1024: false, 250: false, 301: true} {
+		if filter.Match(size) != expected {
+			t.Fatalf("expected Match(%d)=%v", size, expected)
+		}
+	}
+
+	if _, err := parseSizeFilter("300-200"); err == nil {
+		t.Fatalf("expected error for inverted range")
+	}
+}
+
+func TestDiscoveryCandidatesAreGeneratedLazily(t *testing.T) {
+	candidates := discoveryCandidates{targets: []string{"http://a/", "http://b/app"}, paths: []string{"x", "y/"}}
+	if candidates.count() != 4 {
+		t.Fatalf("expected 4 candidates, got %d", candidates.count())
+	}
+
+	var urls []string
+	for entry := range candidates.entries() {
+		urls = append(urls, entry.URL)
+		if len(urls) == 3 {
+			break
+		}
+	}
+	expected := []string{"http://a/x", "http://a/y/", "http://b/app/x"}
+	if strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, urls)
+	}
+}
+
+func TestDiscoverCandidatesAreQueuedOnlyAheadOfTheWorkers(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	paths := make([]string, 100000)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("p%d", i)
+	}
+	candidates := discoveryCandidates{targets: []string{server.URL}, paths: paths}
+	var generated atomic.Int64
+	counted := func(yield func(inputEntry) bool) {
+		for entry := range candidates.entries() {
+			generated.Add(1)
+			if !yield(entry) {
+				return
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	total := &atomic.Int64{}
+	total.Store(int64(candidates.count()))
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		_, _ = scanEntries(ctx, feedEntries(ctx, counted), total, nil, ScanRequest{Concurrency: 2, TimeoutSeconds: 30, DisableFavicon: true}, func(ScanRow) bool { return false }, nil)
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	limit := int64(pendingPerWorker*2) + 4
+	if got := generated.Load(); got > limit {
+		t.Fatalf("expected at most %d candidates to be generated while the workers are busy, got %d", limit, got)
+	}
+	cancel()
+	<-finished
+}
+
+func TestDiscoverScanWorkersSkipsFollowUpsForMisses(t *testing.T) {
+	newServer := func(hit string) (*httptest.Server, *atomic.Int64) {
+		icons := &atomic.Int64{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/favicon.ico":
+				icons.Add(1)
+				_, _ = w.Write([]byte("icon"))
+			case hit:
+				_, _ = w.Write([]byte("<title>hit</title>"))
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+		t.Cleanup(server.Close)
+		return server, icons
+	}
+	hits, hitIcons := newServer("/admin")
+	misses, missIcons := newServer("/unused")
+
+	candidates := discoveryCandidates{targets: []string{hits.URL, misses.URL}, paths: []string{"admin", "backup", "login"}}
+	var last ScanProgress
+	rows, err := discoverScanWorkers(context.Background(), candidates, discoveryFilter{status: defaultStatusFilter()}, ScanRequest{Concurrency: 2, TimeoutSeconds: 5}, func(_ ScanRowEvent, progress ScanProgress) {
+		last = progress
+	})
+	if err != nil {
+		t.Fatalf("discoverScanWorkers returned error: %v", err)
+	}
+
+	if len(rows) != 1 || rows[0].URL != hits.URL+"/admin" || rows[0].FaviconURL == "" {
+		t.Fatalf("expected only the enriched hit, got %+v", rows)
+	}
+	if last.Done != 6 || last.Total != 6 {
+		t.Fatalf("expected misses to advance progress, got %+v", last)
+	}
+	if hitIcons.Load() != 1 || missIcons.Load() != 0 {
+		t.Fatalf("expected favicons only for hosts with hits, got %d and %d", hitIcons.Load(), missIcons.Load())
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	reportFormatMarkdown  = "md"
+	reportFormatJSON      = "json"
+	reportFormatCSV       = "csv"
+	reportFormatJSONLines = "jsonl"
+)
+
+var csvReportHeader = []string{
+	"url", "final_url", "status", "title", "components", "content_type",
+	"length", "time_ms", "location", "source_status", "wildcard", "error",
+	"source_files",
+}
+
+// exportFileName names the JSON, CSV or JSON Lines export of a scan after its input, like
+// buildReportFileName does for the Markdown report.
+func exportFileName(inputFilePath, format string) string {
+	return strings.TrimSuffix(buildReportFileName(inputFilePath), ".md") + "." + format
+}
+
+// writeJSONReport writes the whole response, rows included, as indented JSON.
+func writeJSONReport(w io.Writer, response ScanResponse) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(response)
+}
+
+// writeCSVReport writes one line per row with the columns of the Markdown
+// table, in a form spreadsheets and scripts can read.
+func writeCSVReport(w io.Writer, rows []ScanRow) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvReportHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		record := []string{
+			row.URL,
+			row.FinalURL,
+			strconv.Itoa(row.StatusCode),
+			row.Title,
+			strings.Join(row.Components, "; "),
+			row.ContentType,
+			strconv.FormatInt(row.ContentLength, 10),
+			strconv.FormatInt(row.ResponseTimeMs, 10),
+			row.Location,
+			strconv.Itoa(row.SourceStatus),
+			strconv.FormatBool(row.WildcardMatch),
+			row.Error,
+			strings.Join(row.SourceFiles, "; "),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// jsonLinesWriter writes one JSON object per finished row. It keeps the first
+// write error so a scan is not interrupted by a broken output.
+type jsonLinesWriter struct {
+	encoder *json.Encoder
+	err     error
+}
+
+func newJSONLinesWriter(w io.Writer) *jsonLinesWriter {
+	return &jsonLinesWriter{encoder: json.NewEncoder(w)}
+}
+
+func (w *jsonLinesWriter) write(event ScanRowEvent) {
+	if w.err == nil {
+		w.err = w.encoder.Encode(event.Row)
+	}
+}
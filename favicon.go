@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"math/bits"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const maxFaviconSize = 1 << 20
+
+// faviconHash is the icon of one host.
+type faviconHash struct {
+	url  string
+	mmh3 int32
+	md5  string
+}
+
+// faviconFetcher downloads each host's favicon once per scan and stamps the
+// hashes on every row of that host. Icon requests wait for the scheduler like
+// any other request to the host.
+type faviconFetcher struct {
+	client    *http.Client
+	auth      authRules
+	scheduler *hostScheduler
+
+	mu    sync.Mutex
+	hosts map[string]*hostFavicon
+}
+
+type hostFavicon struct {
+	once sync.Once
+	icon *faviconHash
+}
+
+func newFaviconFetcher(client *http.Client, options scanOptions) *faviconFetcher {
+	return &faviconFetcher{client: client, auth: options.auth, scheduler: options.scheduler, hosts: make(map[string]*hostFavicon)}
+}
+
+// Annotate sets the favicon fields of row.
+// The first row of a host decides which icon is fetched: its <link rel="icon">
+// when present, otherwise /favicon.ico.
+func (f *faviconFetcher) Annotate(ctx context.Context, row *ScanRow) {
+	if row.StatusCode == 0 {
+		return
+	}
+
+	pageURL := row.FinalURL
+	if pageURL == "" {
+		pageURL = row.URL
+	}
+	parsed, err := url.Parse(pageURL)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	host := f.host(origin)
+	host.once.Do(func() {
+		candidates := make([]string, 0, 2)
+		if row.pageIconURL != "" {
+			candidates = append(candidates, row.pageIconURL)
+		}
+		candidates = append(candidates, origin+"/favicon.ico")
+		for _, candidate := range candidates {
+			if icon := f.fetch(ctx, candidate, browserProfileByName(row.BrowserProfile)); icon != nil {
+				host.icon = icon
+				return
+			}
+		}
+	})
+
+	if host.icon == nil {
+		return
+	}
+	row.FaviconURL = host.icon.url
+	row.FaviconHash = host.icon.mmh3
+	row.FaviconMD5 = host.icon.md5
+}
+
+func (f *faviconFetcher) host(origin string) *hostFavicon {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	host, ok := f.hosts[origin]
+	if !ok {
+		host = &hostFavicon{}
+		f.hosts[origin] = host
+	}
+	return host
+}
+
+// fetch returns nil unless iconURL answers 200 with something that is not an
+// HTML page, which rules out soft-404s.
+func (f *faviconFetcher) fetch(ctx context.Context, iconURL string, browser browserProfile) *faviconHash {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, iconURL, nil)
+	if err != nil {
+		return nil
+	}
+	for _, header := range browser.headers {
+		req.Header.Set(header.name, header.value)
+	}
+	req.Header.Set("Accept", "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8")
+	f.auth.apply(req)
+
+	if f.scheduler.wait(ctx, iconURL) != nil {
+		return nil
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFaviconSize))
+	if err != nil || len(data) == 0 {
+		return nil
+	}
+
+	sum := md5.Sum(data)
+	return &faviconHash{
+		url:  resp.Request.URL.String(),
+		mmh3: shodanFaviconHash(data),
+		md5:  hex.EncodeToString(sum[:]),
+	}
+}
+
+// shodanFaviconHash reproduces Shodan's and FOFA's icon_hash: MurmurHash3 of
+// the base64 text as Python's base64.encodebytes writes it, i.e. with a
+// newline after every 76 characters and at the end.
+func shodanFaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var builder strings.Builder
+	for len(encoded) > 76 {
+		builder.WriteString(encoded[:76])
+		builder.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	builder.WriteString(encoded)
+	builder.WriteByte('\n')
+
+	return int32(murmur3(0, []byte(builder.String())))
+}
+
+// murmur3 is the 32-bit x86 variant of MurmurHash3.
+func murmur3(seed uint32, data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	hash := seed
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		hash ^= k
+		hash = bits.RotateLeft32(hash, 13)
+		hash = hash*5 + 0xe6546b64
+	}
+
+	var k uint32
+	tail := data[blocks*4:]
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		hash ^= k
+	}
+
+	hash ^= uint32(len(data))
+	hash ^= hash >> 16
+	hash *= 0x85ebca6b
+	hash ^= hash >> 13
+	hash *= 0xc2b2ae35
+	hash ^= hash >> 16
+	return hash
+}
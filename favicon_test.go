@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMurmur3MatchesReferenceVectors(t *testing.T) {
+	cases := []struct {
+		input string
+		seed  uint32
+		want  uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"hello", 0, 0x248bfa47},
+		{"The quick brown fox jumps over the lazy dog", 0, 0x2e4ff723},
+	}
+	for _, tc := range cases {
+		if got := murmur3(tc.seed, []byte(tc.input)); got != tc.want {
+			t.Fatalf("murmur3(%d, %q) = %#x, want %#x", tc.seed, tc.input, got, tc.want)
+		}
+	}
+	if got := int32(murmur3(0, []byte("foo"))); got != -156908512 {
+		t.Fatalf("expected mmh3.hash(\"foo\") = -156908512, got %d", got)
+	}
+}
+
+func TestShodanFaviconHashWrapsBase64Lines(t *testing.T) {
+	data := []byte(strings.Repeat("icon", 40))
+	// base64.encodebytes(b"icon" * 40) in Python.
+	encoded := "aWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25p\n" +
+		"Y29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmlj\n" +
+		"b25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbmljb25pY29uaWNvbg==\n"
+
+	if got, want := shodanFaviconHash(data), int32(murmur3(0, []byte(encoded))); got != want {
+		t.Fatalf("expected hash of the wrapped base64, got %d want %d", got, want)
+	}
+}
+
+func TestRunScanWorkersHashesFaviconOncePerHost(t *testing.T) {
+	icon := []byte("\x00\x00\x01\x00fake icon")
+	var iconRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/static/logo.png":
+			iconRequests.Add(1)
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write(icon)
+		case "/favicon.ico":
+			t.Errorf("expected the <link rel=icon> to be preferred over /favicon.ico")
+		default:
+			_, _ = w.Write([]byte(`<html><head><link rel="shortcut icon" href="static/logo.png"></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	hash := shodanFaviconHash(icon)
+	rulesPath := filepath.Join(t.TempDir(), "rules.yaml")
+	rules := fmt.Sprintf("rules:\n  - name: Test Product\n    matchers:\n      - type: favicon\n        hashes: [%d]\n", hash)
+	if err := os.WriteFile(rulesPath, []byte(rules), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/"), urlEntry(server.URL + "/b")}, ScanRequest{
+		Concurrency:          1,
+		TimeoutSeconds:       5,
+		FingerprintRulePaths: rulesPath,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	if iconRequests.Load() != 1 {
+		t.Fatalf("expected the icon to be fetched once, got %d", iconRequests.Load())
+	}
+	for _, row := range rows {
+		if row.FaviconURL != server.URL+"/static/logo.png" || row.FaviconHash != hash || len(row.FaviconMD5) != 32 {
+			t.Fatalf("expected favicon fields on every row, got %q %d %q", row.FaviconURL, row.FaviconHash, row.FaviconMD5)
+		}
+		if strings.Join(row.Components, ",") != "Test Product" {
+			t.Fatalf("expected favicon match as component, got %v", row.Components)
+		}
+	}
+}
+
+func TestFaviconFallsBackToFaviconICOAndSkipsHTML(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/favicon.ico":
+			_, _ = w.Write([]byte("\x00\x00\x01\x00"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<title>home</title>"))
+		}
+	}))
+	defer server.Close()
+
+	soft404 := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<title>home</title>"))
+	}))
+	defer soft404.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL), urlEntry(soft404.URL)}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	if rows[0].FaviconURL != server.URL+"/favicon.ico" || rows[0].FaviconMD5 == "" {
+		t.Fatalf("expected /favicon.ico fallback, got %+v", rows[0])
+	}
+	if rows[1].FaviconURL != "" || rows[1].FaviconMD5 != "" {
+		t.Fatalf("expected an HTML answer not to count as favicon, got %+v", rows[1])
+	}
+}
+
+func TestFaviconRequestsHonourThePerHostDelay(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		if r.URL.Path == "/favicon.ico" {
+			_, _ = w.Write([]byte("\x00\x00\x01\x00"))
+			return
+		}
+		_, _ = w.Write([]byte("<title>home</title>"))
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL)}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		PerHostDelayMs: 100,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if len(rows) != 1 || rows[0].FaviconMD5 == "" {
+		t.Fatalf("expected the favicon to be fetched, got %+v", rows)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 2 || times[1].Sub(times[0]) < 90*time.Millisecond {
+		t.Fatalf("expected the favicon request to wait for the 100ms per-host delay, got %v", times)
+	}
+}
+
+func TestAppendMarkdownReportListsFaviconHashes(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "scan_report.md")
+	row := ScanRow{URL: "http://example.com/", StatusCode: 200, FaviconURL: "http://example.com/favicon.ico", FaviconHash: 116323821, FaviconMD5: "0488faca4c19046b94d07c3ee83cf9d6"}
+	response := ScanResponse{TotalURLs: 2, Rows: []ScanRow{row, row}}
+
+	if err := appendMarkdownReport(reportPath, "input.txt", response); err != nil {
+		t.Fatalf("appendMarkdownReport returned error: %v", err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+
+	want := "| http://example.com/favicon.ico | 116323821 | 0488faca4c19046b94d07c3ee83cf9d6 |"
+	if strings.Count(string(data), want) != 1 {
+		t.Fatalf("expected favicon listed once as %q:\n%s", want, data)
+	}
+}
@@ -0,0 +1,620 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed fingerprints/default.yaml
+var defaultFingerprintRules []byte
+
+const (
+	matchAny = "or"
+	matchAll = "and"
+)
+
+// fingerprintRuleFile is the native layout of a rule file. YAML is a
+// superset of JSON, so the same decoder reads both. Categories maps category
+// ids used by rules to display names.
+type fingerprintRuleFile struct {
+	Rules      []fingerprintRule `json:"rules" yaml:"rules"`
+	Categories map[string]string `json:"categories" yaml:"categories"`
+}
+
+// fingerprintRule names a product. A rule without matchers only describes the
+// categories and implies of a product that other rules imply. Implies may
+// carry a confidence the Wappalyzer way, e.g. "MySQL\\;confidence:50".
+type fingerprintRule struct {
+	Name       string               `json:"name" yaml:"name"`
+	Condition  string               `json:"condition" yaml:"condition"`
+	Disabled   bool                 `json:"disabled" yaml:"disabled"`
+	Categories []string             `json:"categories" yaml:"categories"`
+	Implies    []string             `json:"implies" yaml:"implies"`
+	Matchers   []fingerprintMatcher `json:"matchers" yaml:"matchers"`
+}
+
+// fingerprintMatcher checks one part of the response. Version is a template
+// filled from the regex groups (see expandVersion). Confidence, 100 when
+// unset, is what the matcher adds to the rule's score; a rule's score is the
+// sum over its matching matchers, capped at 100.
+type fingerprintMatcher struct {
+	Type       string   `json:"type" yaml:"type"`
+	Name       string   `json:"name" yaml:"name"`
+	Condition  string   `json:"condition" yaml:"condition"`
+	Keywords   []string `json:"keywords" yaml:"keywords"`
+	Regex      string   `json:"regex" yaml:"regex"`
+	Version    string   `json:"version" yaml:"version"`
+	Confidence int      `json:"confidence" yaml:"confidence"`
+	Hashes     []int32  `json:"hashes" yaml:"hashes"`
+	MD5        []string `json:"md5" yaml:"md5"`
+}
+
+// fingerprintEvidence is the part of a response the rules look at. It lives
+// on the row only until the worker has identified the components.
+type fingerprintEvidence struct {
+	headers http.Header
+	cookies []string
+	body    string
+	title   string
+	meta    map[string][]string
+	scripts []string
+}
+
+func newFingerprintEvidence(resp *http.Response, body []byte, signals htmlSignals) *fingerprintEvidence {
+	cookies := make([]string, 0)
+	for _, cookie := range resp.Cookies() {
+		cookies = append(cookies, cookie.Name+"="+cookie.Value)
+	}
+	return &fingerprintEvidence{
+		headers: resp.Header,
+		cookies: cookies,
+		body:    string(body),
+		title:   signals.Title,
+		meta:    signals.Meta,
+		scripts: signals.Scripts,
+	}
+}
+
+type compiledRule struct {
+	name     string
+	all      bool
+	matchers []compiledMatcher
+}
+
+type compiledMatcher struct {
+	part       string
+	name       string
+	all        bool
+	keywords   []string
+	regex      *regexp.Regexp
+	version    string
+	confidence int
+	hashes     []int32
+	md5s       []string
+}
+
+type impliedProduct struct {
+	name       string
+	confidence int
+}
+
+// fingerprintEngine holds the compiled rules of a scan, plus the categories
+// and implied products of every product by lower-cased name.
+type fingerprintEngine struct {
+	rules      []compiledRule
+	categories map[string][]string
+	implies    map[string][]impliedProduct
+}
+
+// fingerprintHit is what a matching rule or matcher found.
+type fingerprintHit struct {
+	version    string
+	confidence int
+	evidence   string
+}
+
+// fingerprintMatch is a product recognised by the engine.
+type fingerprintMatch struct {
+	fingerprintHit
+	name       string
+	categories []string
+}
+
+var versionGroupRegex = regexp.MustCompile(`\\(\d+)`)
+
+var defaultFingerprintEngine = sync.OnceValues(func() (*fingerprintEngine, error) {
+	return loadFingerprintEngine(nil)
+})
+
+// loadFingerprintEngine compiles the default rules followed by the rules of
+// every file in paths. A directory stands for all .json, .yaml and .yml
+// files inside it. Besides the native layout, files may be EHole, Wappalyzer
+// or FingerprintHub libraries (see parseFingerprintFile).
+func loadFingerprintEngine(paths []string) (*fingerprintEngine, error) {
+	defaults, err := parseFingerprintFile(defaultFingerprintRules)
+	if err != nil {
+		return nil, fmt.Errorf("default fingerprint rules: %w", err)
+	}
+	rules := defaults.Rules
+	categories := make(map[string]string)
+	mergeCategories(categories, defaults.Categories)
+
+	files, err := expandRulePaths(paths)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("read fingerprint rules: %w", err)
+		}
+		parsed, err := parseFingerprintFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		rules = mergeFingerprintRules(rules, parsed.Rules)
+		mergeCategories(categories, parsed.Categories)
+	}
+
+	return compileFingerprintRules(resolveCategories(rules, categories))
+}
+
+// newScanFingerprintEngine avoids recompiling the default rules for scans
+// that add none of their own.
+func newScanFingerprintEngine(paths []string) (*fingerprintEngine, error) {
+	if len(paths) == 0 {
+		return defaultFingerprintEngine()
+	}
+	return loadFingerprintEngine(paths)
+}
+
+// splitRulePaths reads FingerprintRulePaths: one path per line.
+func splitRulePaths(spec string) []string {
+	paths := make([]string, 0)
+	for _, line := range strings.Split(spec, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
+
+func expandRulePaths(paths []string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("read fingerprint rules: %w", err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("read fingerprint rules: %w", err)
+		}
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			switch strings.ToLower(filepath.Ext(entry.Name())) {
+			case ".json", ".yaml", ".yml":
+				if !entry.IsDir() {
+					names = append(names, filepath.Join(path, entry.Name()))
+				}
+			}
+		}
+		sort.Strings(names)
+		files = append(files, names...)
+	}
+	return files, nil
+}
+
+func parseNativeFingerprintRules(data []byte) (fingerprintRuleFile, error) {
+	var file fingerprintRuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fingerprintRuleFile{}, err
+	}
+	return file, nil
+}
+
+// mergeFingerprintRules appends the rules of a later file. Rules sharing a
+// name within one file are alternatives; a name defined by an earlier file
+// is replaced by all rules of that name in the later one. Enabled rules
+// without matchers, such as a Wappalyzer technology known only by its js or
+// dom patterns, replace nothing and only add their categories and implies.
+func mergeFingerprintRules(base, extra []fingerprintRule) []fingerprintRule {
+	replaced := make(map[string]struct{}, len(extra))
+	for _, rule := range extra {
+		if len(rule.Matchers) > 0 || rule.Disabled {
+			replaced[strings.ToLower(rule.Name)] = struct{}{}
+		}
+	}
+
+	merged := make([]fingerprintRule, 0, len(base)+len(extra))
+	for _, rule := range base {
+		if _, ok := replaced[strings.ToLower(rule.Name)]; !ok {
+			merged = append(merged, rule)
+		}
+	}
+	return append(merged, extra...)
+}
+
+func mergeCategories(categories, extra map[string]string) {
+	for id, name := range extra {
+		categories[id] = name
+	}
+}
+
+// resolveCategories replaces category ids, such as Wappalyzer's numeric
+// "cats", with their names.
+func resolveCategories(rules []fingerprintRule, categories map[string]string) []fingerprintRule {
+	for i := range rules {
+		names := make([]string, 0, len(rules[i].Categories))
+		for _, category := range rules[i].Categories {
+			if name, ok := categories[category]; ok {
+				category = name
+			}
+			names = append(names, category)
+		}
+		rules[i].Categories = names
+	}
+	return rules
+}
+
+func compileFingerprintRules(rules []fingerprintRule) (*fingerprintEngine, error) {
+	engine := &fingerprintEngine{
+		rules:      make([]compiledRule, 0, len(rules)),
+		categories: make(map[string][]string),
+		implies:    make(map[string][]impliedProduct),
+	}
+	for _, rule := range rules {
+		if rule.Disabled {
+			continue
+		}
+		if strings.TrimSpace(rule.Name) == "" {
+			return nil, fmt.Errorf("fingerprint rule without name")
+		}
+
+		key := strings.ToLower(strings.TrimSpace(rule.Name))
+		engine.categories[key] = appendUnique(engine.categories[key], rule.Categories...)
+		for _, implied := range rule.Implies {
+			engine.implies[key] = appendImplied(engine.implies[key], implied)
+		}
+
+		all, err := parseMatchCondition(rule.Condition)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+		}
+		compiled := compiledRule{name: strings.TrimSpace(rule.Name), all: all}
+
+		for _, matcher := range rule.Matchers {
+			compiledMatcher, err := compileMatcher(matcher)
+			if err != nil {
+				return nil, fmt.Errorf("rule %q: %w", rule.Name, err)
+			}
+			compiled.matchers = append(compiled.matchers, compiledMatcher)
+		}
+		if len(compiled.matchers) == 0 {
+			if len(rule.Categories) > 0 || len(rule.Implies) > 0 {
+				continue
+			}
+			return nil, fmt.Errorf("rule %q has no matchers", rule.Name)
+		}
+		engine.rules = append(engine.rules, compiled)
+	}
+	return engine, nil
+}
+
+func parseMatchCondition(condition string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(condition)) {
+	case "", matchAny:
+		return false, nil
+	case matchAll:
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown condition %q", condition)
+	}
+}
+
+func compileMatcher(matcher fingerprintMatcher) (compiledMatcher, error) {
+	part := strings.ToLower(strings.TrimSpace(matcher.Type))
+	switch part {
+	case "header", "cookie", "body", "title", "meta", "script", "favicon":
+	default:
+		return compiledMatcher{}, fmt.Errorf("unknown matcher type %q", matcher.Type)
+	}
+
+	all, err := parseMatchCondition(matcher.Condition)
+	if err != nil {
+		return compiledMatcher{}, err
+	}
+
+	if matcher.Confidence < 0 || matcher.Confidence > fullConfidence {
+		return compiledMatcher{}, fmt.Errorf("confidence %d out of range 0-100", matcher.Confidence)
+	}
+	compiled := compiledMatcher{
+		part:       part,
+		name:       strings.ToLower(strings.TrimSpace(matcher.Name)),
+		all:        all,
+		version:    matcher.Version,
+		confidence: matcher.Confidence,
+		hashes:     matcher.Hashes,
+	}
+	if compiled.confidence == 0 {
+		compiled.confidence = fullConfidence
+	}
+	for _, md5 := range matcher.MD5 {
+		compiled.md5s = append(compiled.md5s, strings.ToLower(strings.TrimSpace(md5)))
+	}
+	for _, keyword := range matcher.Keywords {
+		if keyword = strings.ToLower(keyword); keyword != "" {
+			compiled.keywords = append(compiled.keywords, keyword)
+		}
+	}
+	if matcher.Regex != "" {
+		compiled.regex, err = regexp.Compile(matcher.Regex)
+		if err != nil {
+			return compiledMatcher{}, fmt.Errorf("invalid regex %q: %w", matcher.Regex, err)
+		}
+	}
+
+	if part == "favicon" && len(compiled.hashes) == 0 && len(compiled.md5s) == 0 {
+		return compiledMatcher{}, fmt.Errorf("favicon matcher without hashes")
+	}
+	if part != "favicon" && len(compiled.keywords) == 0 && compiled.regex == nil {
+		return compiledMatcher{}, fmt.Errorf("%s matcher without keywords or regex", part)
+	}
+	return compiled, nil
+}
+
+// Identify returns the products matching the row in rule order, followed by
+// the products they imply.
+func (e *fingerprintEngine) Identify(evidence *fingerprintEvidence, row ScanRow) []fingerprintMatch {
+	matches := make([]fingerprintMatch, 0)
+	if evidence == nil {
+		return matches
+	}
+
+	seen := make(map[string]int)
+	for _, rule := range e.rules {
+		hit, ok := rule.matches(evidence, row)
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(rule.name)
+		if i, ok := seen[key]; ok {
+			matches[i].fingerprintHit = matches[i].combine(hit)
+			continue
+		}
+		seen[key] = len(matches)
+		matches = append(matches, fingerprintMatch{fingerprintHit: hit, name: rule.name, categories: e.categories[key]})
+	}
+
+	for i := 0; i < len(matches); i++ {
+		for _, implied := range e.implies[strings.ToLower(matches[i].name)] {
+			key := strings.ToLower(implied.name)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = len(matches)
+			matches = append(matches, fingerprintMatch{
+				fingerprintHit: fingerprintHit{
+					confidence: matches[i].confidence * implied.confidence / fullConfidence,
+					evidence:   "implied by " + matches[i].name,
+				},
+				name:       implied.name,
+				categories: e.categories[key],
+			})
+		}
+	}
+	return matches
+}
+
+// combine adds up the confidence of two hits and keeps the first version and
+// evidence found.
+func (h fingerprintHit) combine(other fingerprintHit) fingerprintHit {
+	if h.version == "" {
+		h.version = other.version
+	}
+	if h.evidence == "" {
+		h.evidence = other.evidence
+	}
+	h.confidence = min(h.confidence+other.confidence, fullConfidence)
+	return h
+}
+
+// matches reports whether the rule matches, combining the hits of all its
+// matching matchers.
+func (r compiledRule) matches(evidence *fingerprintEvidence, row ScanRow) (fingerprintHit, bool) {
+	matched := false
+	total := fingerprintHit{}
+	for _, matcher := range r.matchers {
+		hit, ok := matcher.matches(evidence, row)
+		if !ok {
+			if r.all {
+				return fingerprintHit{}, false
+			}
+			continue
+		}
+		matched = true
+		total = total.combine(hit)
+	}
+	return total, matched
+}
+
+func (m compiledMatcher) matches(evidence *fingerprintEvidence, row ScanRow) (fingerprintHit, bool) {
+	if m.part == "favicon" {
+		if row.FaviconMD5 == "" {
+			return fingerprintHit{}, false
+		}
+		if slices.Contains(m.hashes, row.FaviconHash) {
+			return fingerprintHit{confidence: m.confidence, evidence: fmt.Sprintf("favicon mmh3 %d", row.FaviconHash)}, true
+		}
+		if slices.Contains(m.md5s, row.FaviconMD5) {
+			return fingerprintHit{confidence: m.confidence, evidence: "favicon md5 " + row.FaviconMD5}, true
+		}
+		return fingerprintHit{}, false
+	}
+
+	for _, text := range m.targets(evidence) {
+		if version, snippet, ok := m.matchText(text); ok {
+			return fingerprintHit{version: version, confidence: m.confidence, evidence: truncateEvidence(m.describe(snippet))}, true
+		}
+	}
+	return fingerprintHit{}, false
+}
+
+// describe prefixes the matched snippet with where it was found.
+func (m compiledMatcher) describe(snippet string) string {
+	switch {
+	case m.name != "" && m.part == "header":
+		return http.CanonicalHeaderKey(m.name) + ": " + snippet
+	case m.name != "":
+		return m.part + " " + m.name + ": " + snippet
+	default:
+		return m.part + ": " + snippet
+	}
+}
+
+// targets returns the strings of the response this matcher looks at.
+func (m compiledMatcher) targets(evidence *fingerprintEvidence) []string {
+	switch m.part {
+	case "header":
+		if m.name != "" {
+			return evidence.headers.Values(m.name)
+		}
+		lines := make([]string, 0, len(evidence.headers))
+		for name, values := range evidence.headers {
+			for _, value := range values {
+				lines = append(lines, name+": "+value)
+			}
+		}
+		return lines
+	case "cookie":
+		if m.name == "" {
+			return evidence.cookies
+		}
+		values := make([]string, 0)
+		for _, cookie := range evidence.cookies {
+			if name, value, _ := strings.Cut(cookie, "="); strings.EqualFold(name, m.name) {
+				values = append(values, value)
+			}
+		}
+		return values
+	case "body":
+		return []string{evidence.body}
+	case "title":
+		return []string{evidence.title}
+	case "meta":
+		if m.name != "" {
+			return evidence.meta[m.name]
+		}
+		pairs := make([]string, 0, len(evidence.meta))
+		for name, contents := range evidence.meta {
+			for _, content := range contents {
+				pairs = append(pairs, name+"="+content)
+			}
+		}
+		return pairs
+	case "script":
+		return evidence.scripts
+	default:
+		return nil
+	}
+}
+
+// matchText returns the version captured from text and the snippet that
+// matched: the regex match, or the keywords found.
+func (m compiledMatcher) matchText(text string) (string, string, bool) {
+	if m.regex != nil {
+		if groups := m.regex.FindStringSubmatch(text); groups != nil {
+			snippet := groups[0]
+			if snippet == "" {
+				snippet = text
+			}
+			return expandVersion(m.version, groups), snippet, true
+		}
+	}
+	if len(m.keywords) == 0 {
+		return "", "", false
+	}
+
+	lower := strings.ToLower(text)
+	for _, keyword := range m.keywords {
+		found := strings.Contains(lower, keyword)
+		if found && !m.all {
+			return "", keyword, true
+		}
+		if !found && m.all {
+			return "", "", false
+		}
+	}
+	return "", strings.Join(m.keywords, " + "), m.all
+}
+
+// expandVersion fills a version template from regex groups the way
+// Wappalyzer does: \1 stands for the first group, and "a?b:c" yields b when
+// a expands to something and c otherwise.
+func expandVersion(template string, groups []string) string {
+	if template == "" {
+		return ""
+	}
+	if condition, branches, ok := strings.Cut(template, "?"); ok {
+		yes, no, _ := strings.Cut(branches, ":")
+		if expandVersion(condition, groups) != "" {
+			template = yes
+		} else {
+			template = no
+		}
+	}
+
+	version := versionGroupRegex.ReplaceAllStringFunc(template, func(ref string) string {
+		index, _ := strconv.Atoi(ref[1:])
+		if index < len(groups) {
+			return groups[index]
+		}
+		return ""
+	})
+	return strings.TrimSpace(version)
+}
+
+// appendImplied parses an implies entry such as "PHP" or
+// "MySQL\\;confidence:50" and adds it unless already listed.
+func appendImplied(implied []impliedProduct, entry string) []impliedProduct {
+	name, tags := splitWappalyzerPattern(entry)
+	if name == "" {
+		return implied
+	}
+	for _, product := range implied {
+		if strings.EqualFold(product.name, name) {
+			return implied
+		}
+	}
+
+	confidence, err := strconv.Atoi(tags["confidence"])
+	if err != nil || confidence <= 0 || confidence > fullConfidence {
+		confidence = fullConfidence
+	}
+	return append(implied, impliedProduct{name: name, confidence: confidence})
+}
+
+func appendUnique(values []string, extra ...string) []string {
+	for _, value := range extra {
+		if value = strings.TrimSpace(value); value != "" && !slices.Contains(values, value) {
+			values = append(values, value)
+		}
+	}
+	return values
+}
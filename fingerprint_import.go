@@ -0,0 +1,355 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseFingerprintFile reads a rule file and recognises its layout:
+//
+//   - native rules ({"rules": [...]}, JSON or YAML);
+//   - EHole finger.json ({"fingerprint": [...]});
+//   - Wappalyzer technologies/*.json (products keyed by name), its
+//     categories.json (keyed by numeric id), or a combined apps.json;
+//   - FingerprintHub web_fingerprint_v3.json (a top-level array).
+func parseFingerprintFile(data []byte) (fingerprintRuleFile, error) {
+	trimmed := bytes.TrimSpace(bytes.TrimPrefix(data, utf8BOM))
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return parseFingerprintHubRules(trimmed)
+	case bytes.HasPrefix(trimmed, []byte("{")):
+	default:
+		return parseNativeFingerprintRules(trimmed)
+	}
+
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &document); err != nil {
+		return fingerprintRuleFile{}, err
+	}
+
+	switch {
+	case document["rules"] != nil:
+		return parseNativeFingerprintRules(trimmed)
+	case document["fingerprint"] != nil:
+		return parseEHoleRules(document["fingerprint"])
+	case document["technologies"] != nil || document["apps"] != nil:
+		return parseWappalyzerBundle(document)
+	case len(document) > 0 && allDigitKeys(document):
+		categories, err := parseWappalyzerCategories(trimmed)
+		return fingerprintRuleFile{Categories: categories}, err
+	default:
+		return parseWappalyzerTechnologies(trimmed)
+	}
+}
+
+// stringList accepts a JSON string or an array of strings.
+type stringList []string
+
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*l = stringList{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+type eholeFingerprint struct {
+	CMS      string   `json:"cms"`
+	Method   string   `json:"method"`
+	Location string   `json:"location"`
+	Keyword  []string `json:"keyword"`
+}
+
+// parseEHoleRules converts EHole fingerprints. All keywords of an entry must
+// match; entries sharing a cms are alternatives.
+func parseEHoleRules(raw json.RawMessage) (fingerprintRuleFile, error) {
+	var fingerprints []eholeFingerprint
+	if err := json.Unmarshal(raw, &fingerprints); err != nil {
+		return fingerprintRuleFile{}, err
+	}
+
+	rules := make([]fingerprintRule, 0, len(fingerprints))
+	for _, fingerprint := range fingerprints {
+		if rule, ok := fingerprint.rule(); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return fingerprintRuleFile{Rules: rules}, nil
+}
+
+func (f eholeFingerprint) rule() (fingerprintRule, bool) {
+	rule := fingerprintRule{Name: strings.TrimSpace(f.CMS), Condition: matchAll}
+	keywords := nonEmpty(f.Keyword)
+	if rule.Name == "" || len(keywords) == 0 {
+		return fingerprintRule{}, false
+	}
+
+	location := strings.ToLower(f.Location)
+	switch location {
+	case "body", "header", "title":
+	default:
+		return fingerprintRule{}, false
+	}
+
+	switch strings.ToLower(f.Method) {
+	case "faviconhash":
+		matcher := fingerprintMatcher{Type: "favicon"}
+		for _, keyword := range keywords {
+			hash, err := strconv.ParseInt(strings.TrimSpace(keyword), 10, 32)
+			if err != nil {
+				return fingerprintRule{}, false
+			}
+			matcher.Hashes = append(matcher.Hashes, int32(hash))
+		}
+		rule.Condition = matchAny
+		rule.Matchers = append(rule.Matchers, matcher)
+	case "keyword":
+		for _, keyword := range keywords {
+			rule.Matchers = append(rule.Matchers, fingerprintMatcher{Type: location, Keywords: []string{keyword}})
+		}
+	case "regula":
+		for _, pattern := range keywords {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fingerprintRule{}, false
+			}
+			rule.Matchers = append(rule.Matchers, fingerprintMatcher{Type: location, Regex: pattern})
+		}
+	default:
+		return fingerprintRule{}, false
+	}
+	return rule, true
+}
+
+type wappalyzerTechnology struct {
+	Cats      []int                 `json:"cats"`
+	Headers   map[string]string     `json:"headers"`
+	Cookies   map[string]string     `json:"cookies"`
+	Meta      map[string]stringList `json:"meta"`
+	HTML      stringList            `json:"html"`
+	Text      stringList            `json:"text"`
+	ScriptSrc stringList            `json:"scriptSrc"`
+	Script    stringList            `json:"script"`
+	Implies   stringList            `json:"implies"`
+}
+
+type wappalyzerCategory struct {
+	Name string `json:"name"`
+}
+
+func parseWappalyzerBundle(document map[string]json.RawMessage) (fingerprintRuleFile, error) {
+	technologies := document["technologies"]
+	if technologies == nil {
+		technologies = document["apps"]
+	}
+
+	file, err := parseWappalyzerTechnologies(technologies)
+	if err != nil {
+		return fingerprintRuleFile{}, err
+	}
+	if document["categories"] != nil {
+		file.Categories, err = parseWappalyzerCategories(document["categories"])
+	}
+	return file, err
+}
+
+func parseWappalyzerCategories(raw json.RawMessage) (map[string]string, error) {
+	var categories map[string]wappalyzerCategory
+	if err := json.Unmarshal(raw, &categories); err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string, len(categories))
+	for id, category := range categories {
+		if category.Name != "" {
+			names[id] = category.Name
+		}
+	}
+	return names, nil
+}
+
+// parseWappalyzerTechnologies converts Wappalyzer technologies. Any pattern
+// detects the product. Patterns Go's regexp cannot compile (lookarounds,
+// backreferences) are dropped, as are the parts of a technology the scanner
+// cannot see, such as js and dom.
+func parseWappalyzerTechnologies(raw json.RawMessage) (fingerprintRuleFile, error) {
+	var technologies map[string]wappalyzerTechnology
+	if err := json.Unmarshal(raw, &technologies); err != nil {
+		return fingerprintRuleFile{}, fmt.Errorf("unrecognised fingerprint file: %w", err)
+	}
+
+	names := make([]string, 0, len(technologies))
+	for name := range technologies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rules := make([]fingerprintRule, 0, len(names))
+	for _, name := range names {
+		rule := technologies[name].rule(name)
+		if len(rule.Matchers) > 0 || len(rule.Categories) > 0 || len(rule.Implies) > 0 {
+			rules = append(rules, rule)
+		}
+	}
+	return fingerprintRuleFile{Rules: rules}, nil
+}
+
+func (t wappalyzerTechnology) rule(name string) fingerprintRule {
+	rule := fingerprintRule{Name: name}
+	for _, category := range t.Cats {
+		rule.Categories = append(rule.Categories, strconv.Itoa(category))
+	}
+	rule.Implies = t.Implies
+
+	add := func(part, key, pattern string) {
+		if matcher, ok := wappalyzerMatcher(part, key, pattern); ok {
+			rule.Matchers = append(rule.Matchers, matcher)
+		}
+	}
+	for _, header := range sortedKeys(t.Headers) {
+		add("header", header, t.Headers[header])
+	}
+	for _, cookie := range sortedKeys(t.Cookies) {
+		add("cookie", cookie, t.Cookies[cookie])
+	}
+	for _, meta := range sortedKeys(t.Meta) {
+		for _, pattern := range t.Meta[meta] {
+			add("meta", strings.ToLower(meta), pattern)
+		}
+	}
+	for _, patterns := range []stringList{t.HTML, t.Text} {
+		for _, pattern := range patterns {
+			add("body", "", pattern)
+		}
+	}
+	for _, patterns := range []stringList{t.ScriptSrc, t.Script} {
+		for _, pattern := range patterns {
+			add("script", "", pattern)
+		}
+	}
+	return rule
+}
+
+// wappalyzerMatcher converts a pattern such as
+// `WordPress ([\d.]+)\;version:\1\;confidence:50`. Wappalyzer patterns are
+// case-insensitive, and an empty one only requires the header, cookie or
+// meta tag to be present.
+func wappalyzerMatcher(part, name, raw string) (fingerprintMatcher, bool) {
+	pattern, tags := splitWappalyzerPattern(raw)
+	if pattern == "" && name == "" {
+		return fingerprintMatcher{}, false
+	}
+
+	regex := "(?i)" + pattern
+	if _, err := regexp.Compile(regex); err != nil {
+		return fingerprintMatcher{}, false
+	}
+
+	matcher := fingerprintMatcher{Type: part, Name: name, Regex: regex, Version: tags["version"]}
+	if confidence, err := strconv.Atoi(tags["confidence"]); err == nil && confidence > 0 && confidence <= fullConfidence {
+		matcher.Confidence = confidence
+	}
+	return matcher, true
+}
+
+func splitWappalyzerPattern(raw string) (string, map[string]string) {
+	fields := strings.Split(raw, `\;`)
+	tags := make(map[string]string, len(fields)-1)
+	for _, field := range fields[1:] {
+		if key, value, ok := strings.Cut(field, ":"); ok {
+			tags[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return strings.TrimSpace(fields[0]), tags
+}
+
+type fingerprintHubRule struct {
+	Name          string            `json:"name"`
+	Path          string            `json:"path"`
+	RequestMethod string            `json:"request_method"`
+	Headers       map[string]string `json:"headers"`
+	Keyword       []string          `json:"keyword"`
+	FaviconHash   []string          `json:"favicon_hash"`
+}
+
+// parseFingerprintHubRules converts FingerprintHub web fingerprints. Every
+// header and keyword of an entry must match and any favicon MD5. Entries
+// that need their own request (another path or method) are skipped.
+func parseFingerprintHubRules(data []byte) (fingerprintRuleFile, error) {
+	var entries []fingerprintHubRule
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fingerprintRuleFile{}, fmt.Errorf("unrecognised fingerprint file: %w", err)
+	}
+
+	rules := make([]fingerprintRule, 0, len(entries))
+	for _, entry := range entries {
+		if rule, ok := entry.rule(); ok {
+			rules = append(rules, rule)
+		}
+	}
+	return fingerprintRuleFile{Rules: rules}, nil
+}
+
+func (e fingerprintHubRule) rule() (fingerprintRule, bool) {
+	if path := strings.TrimSpace(e.Path); path != "" && path != "/" {
+		return fingerprintRule{}, false
+	}
+	if method := strings.ToLower(e.RequestMethod); method != "" && method != "get" {
+		return fingerprintRule{}, false
+	}
+
+	rule := fingerprintRule{Name: strings.TrimSpace(e.Name), Condition: matchAll}
+	for _, header := range sortedKeys(e.Headers) {
+		matcher := fingerprintMatcher{Type: "header", Name: header, Keywords: nonEmpty([]string{e.Headers[header]})}
+		if len(matcher.Keywords) == 0 {
+			matcher.Regex = "^"
+		}
+		rule.Matchers = append(rule.Matchers, matcher)
+	}
+	if keywords := nonEmpty(e.Keyword); len(keywords) > 0 {
+		rule.Matchers = append(rule.Matchers, fingerprintMatcher{Type: "body", Condition: matchAll, Keywords: keywords})
+	}
+	if hashes := nonEmpty(e.FaviconHash); len(hashes) > 0 {
+		rule.Matchers = append(rule.Matchers, fingerprintMatcher{Type: "favicon", MD5: hashes})
+	}
+	return rule, rule.Name != "" && len(rule.Matchers) > 0
+}
+
+func nonEmpty(values []string) []string {
+	kept := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			kept = append(kept, value)
+		}
+	}
+	return kept
+}
+
+func allDigitKeys(document map[string]json.RawMessage) bool {
+	for key := range document {
+		if !isDigits(key) {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
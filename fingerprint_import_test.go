@@ -0,0 +1,204 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func importTestEvidence(headers http.Header, body string) *fingerprintEvidence {
+	resp := &http.Response{Header: headers}
+	return newFingerprintEvidence(resp, []byte(body), extractHTMLSignals([]byte(body)))
+}
+
+func TestParseEHoleFingerprints(t *testing.T) {
+	source := `{"fingerprint": [
+		{"cms": "Shiro", "method": "keyword", "location": "header", "keyword": ["rememberMe=deleteMe"]},
+		{"cms": "Seeyon", "method": "keyword", "location": "body", "keyword": ["/seeyon/", "A8"]},
+		{"cms": "Seeyon", "method": "keyword", "location": "title", "keyword": ["OA"]},
+		{"cms": "Jeecg", "method": "regula", "location": "body", "keyword": ["jeecg-boot v[0-9]"]},
+		{"cms": "Spring", "method": "faviconhash", "location": "body", "keyword": ["116323821"]},
+		{"cms": "Broken", "method": "regula", "location": "body", "keyword": ["(?<=x)"]},
+		{"cms": "Unknown", "method": "keyword", "location": "url", "keyword": ["x"]}
+	]}`
+	file, err := parseFingerprintFile([]byte(source))
+	if err != nil {
+		t.Fatalf("parseFingerprintFile returned error: %v", err)
+	}
+	engine, err := compileFingerprintRules(file.Rules)
+	if err != nil {
+		t.Fatalf("compileFingerprintRules returned error: %v", err)
+	}
+	if len(engine.rules) != 5 {
+		t.Fatalf("expected the unusable entries to be skipped, got %d rules", len(engine.rules))
+	}
+
+	headers := http.Header{}
+	headers.Add("Set-Cookie", "rememberMe=deleteMe; Path=/")
+	evidence := importTestEvidence(headers, `<title>OA</title><script src="/seeyon/main.js"></script> A8 jeecg-boot v3`)
+	got := matchNames(engine.Identify(evidence, ScanRow{FaviconHash: 116323821, FaviconMD5: "x"}))
+	want := []string{"Shiro", "Seeyon", "Jeecg", "Spring"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	evidence = importTestEvidence(http.Header{}, `/seeyon/ only`)
+	if got := matchNames(engine.Identify(evidence, ScanRow{})); len(got) != 0 {
+		t.Fatalf("expected all keywords of an entry to be required, got %v", got)
+	}
+}
+
+func TestParseWappalyzerTechnologies(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"categories.json": `{"1": {"name": "CMS", "priority": 1}, "27": {"name": "Programming languages", "priority": 4}}`,
+		"w.json": `{
+			"WordPress": {
+				"cats": [1],
+				"meta": {"generator": "^WordPress ?([\\d.]+)?\\;version:\\1"},
+				"html": ["<link rel=[\"']stylesheet[\"'] [^>]+/wp-(?:content|includes)/"],
+				"scriptSrc": "/wp-(?:content|includes)/",
+				"implies": ["PHP", "MySQL\\;confidence:50"]
+			},
+			"Broken": {"cats": [1], "html": "(?!lookahead)"}
+		}`,
+		"p.json": `{
+			"PHP": {"cats": [27], "headers": {"X-Powered-By": "^php/?([\\d.]+)?\\;version:\\1"}, "cookies": {"PHPSESSID": ""}},
+			"MySQL": {"cats": [2]}
+		}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	engine, err := loadFingerprintEngine([]string{dir})
+	if err != nil {
+		t.Fatalf("loadFingerprintEngine returned error: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set("X-Powered-By", "PHP/8.1.2")
+	evidence := importTestEvidence(headers, `<meta name="generator" content="WordPress 6.4.2">`)
+	matches := engine.Identify(evidence, ScanRow{})
+	if got, want := matchNames(matches), []string{"PHP 8.1.2", "WordPress 6.4.2", "MySQL"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if got := matches[1].categories; !reflect.DeepEqual(got, []string{"CMS"}) {
+		t.Fatalf("expected category names from categories.json, got %v", got)
+	}
+	if got := matches[2].categories; !reflect.DeepEqual(got, []string{"2"}) {
+		t.Fatalf("expected unknown category ids to be kept, got %v", got)
+	}
+
+	evidence = importTestEvidence(http.Header{"Set-Cookie": {"phpsessid=1"}}, `<script src="/wp-includes/js/wp-embed.min.js"></script>`)
+	if got, want := matchNames(engine.Identify(evidence, ScanRow{})), []string{"PHP", "WordPress", "MySQL"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected script and cookie presence matches, got %v", got)
+	}
+
+	for _, rule := range engine.rules {
+		if rule.name == "Broken" {
+			t.Fatalf("expected patterns Go cannot compile to be dropped")
+		}
+	}
+}
+
+func TestParseWappalyzerBundle(t *testing.T) {
+	source := `{"categories": {"18": {"name": "Web frameworks"}}, "apps": {"Laravel": {"cats": [18], "cookies": {"laravel_session": ""}}}}`
+	engine, err := loadFingerprintEngineFromSource(t, source)
+	if err != nil {
+		t.Fatalf("loadFingerprintEngine returned error: %v", err)
+	}
+
+	matches := engine.Identify(importTestEvidence(http.Header{"Set-Cookie": {"laravel_session=abc"}}, ""), ScanRow{})
+	if len(matches) != 1 || matches[0].name != "Laravel" || !reflect.DeepEqual(matches[0].categories, []string{"Web frameworks"}) {
+		t.Fatalf("expected Laravel as a web framework, got %+v", matches)
+	}
+}
+
+func TestImportedRulesWithoutMatchersKeepTheDefaults(t *testing.T) {
+	source := `{"jQuery": {"cats": [59], "js": {"jQuery.fn.jquery": "([\\d.]+)\\;version:\\1"}, "dom": "script[src*='jquery']"}}`
+	engine, err := loadFingerprintEngineFromSource(t, source)
+	if err != nil {
+		t.Fatalf("loadFingerprintEngine returned error: %v", err)
+	}
+
+	matches := engine.Identify(importTestEvidence(http.Header{}, `<script src="/static/jquery-3.6.0.min.js"></script>`), ScanRow{})
+	if got := matchNames(matches); !contains(got, "jQuery 3.6.0") {
+		t.Fatalf("expected the default jQuery rule to be kept, got %v", got)
+	}
+	if !contains(engine.categories["jquery"], "59") {
+		t.Fatalf("expected the imported categories to be added, got %v", engine.categories["jquery"])
+	}
+}
+
+func TestParseFingerprintHubRules(t *testing.T) {
+	source := `[
+		{"path": "/", "request_method": "get", "name": "nacos", "headers": {"Server": "nacos"}, "keyword": ["<title>Nacos</title>", "console-ui"], "favicon_hash": []},
+		{"path": "/", "request_method": "get", "name": "grafana", "headers": {}, "keyword": [], "favicon_hash": ["0488FACA4C19046B94D07C3EE83CF9D6"]},
+		{"path": "/actuator", "request_method": "get", "name": "actuator", "headers": {}, "keyword": ["_links"], "favicon_hash": []},
+		{"path": "/", "request_method": "post", "name": "post-only", "headers": {}, "keyword": ["x"], "favicon_hash": []}
+	]`
+	engine, err := loadFingerprintEngineFromSource(t, source)
+	if err != nil {
+		t.Fatalf("loadFingerprintEngine returned error: %v", err)
+	}
+
+	headers := http.Header{}
+	headers.Set("Server", "Nacos 2.x")
+	evidence := importTestEvidence(headers, `<title>Nacos</title><div id="console-ui"></div>_links`)
+	got := matchNames(engine.Identify(evidence, ScanRow{FaviconMD5: "0488faca4c19046b94d07c3ee83cf9d6"}))
+	for _, name := range []string{"nacos", "grafana"} {
+		if !contains(got, name) {
+			t.Fatalf("expected %s in %v", name, got)
+		}
+	}
+	if contains(got, "actuator") || contains(got, "post-only") {
+		t.Fatalf("expected rules needing their own request to be skipped, got %v", got)
+	}
+
+	evidence = importTestEvidence(http.Header{}, `<title>Nacos</title><div id="console-ui"></div>`)
+	if got := matchNames(engine.Identify(evidence, ScanRow{})); contains(got, "nacos") {
+		t.Fatalf("expected the header to be required as well, got %v", got)
+	}
+}
+
+func TestExpandVersion(t *testing.T) {
+	groups := []string{"WordPress 6.4", "6.4", ""}
+	cases := map[string]string{
+		"":             "",
+		`\1`:           "6.4",
+		`v\1`:          "v6.4",
+		`\2`:           "",
+		`\1?new:old`:   "new",
+		`\2?new:old`:   "old",
+		`\1?\1:legacy`: "6.4",
+		`\9`:           "",
+	}
+	for template, want := range cases {
+		if got := expandVersion(template, groups); got != want {
+			t.Fatalf("expandVersion(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func loadFingerprintEngineFromSource(t *testing.T, source string) (*fingerprintEngine, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "library.json")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatalf("write library: %v", err)
+	}
+	return loadFingerprintEngine([]string{path})
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
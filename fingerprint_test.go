@@ -0,0 +1,245 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func testEvidence() *fingerprintEvidence {
+	headers := http.Header{}
+	headers.Set("X-Powered-By", "PHP/8.2")
+	headers.Add("Set-Cookie", "PHPSESSID=abc; Path=/")
+	body := `<html><head><title>Admin Console</title>` +
+		`<meta name="generator" content="Hugo 0.120">` +
+		`<script src="/static/js/app.chunk.js"></script></head>` +
+		`<body><a href="/index.php?id=1">x</a></body></html>`
+	resp := &http.Response{Header: headers}
+	return newFingerprintEvidence(resp, []byte(body), extractHTMLSignals([]byte(body)))
+}
+
+func compileTestRules(t *testing.T, source string) *fingerprintEngine {
+	t.Helper()
+	file, err := parseFingerprintFile([]byte(source))
+	if err != nil {
+		t.Fatalf("parseFingerprintFile returned error: %v", err)
+	}
+	engine, err := compileFingerprintRules(file.Rules)
+	if err != nil {
+		t.Fatalf("compileFingerprintRules returned error: %v", err)
+	}
+	return engine
+}
+
+func matchNames(matches []fingerprintMatch) []string {
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		name := match.name
+		if match.version != "" {
+			name += " " + match.version
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestFingerprintMatcherTypes(t *testing.T) {
+	engine := compileTestRules(t, `
+rules:
+  - name: header
+    matchers: [{type: header, name: x-powered-by, keywords: ["php/"]}]
+  - name: header-any
+    matchers: [{type: header, regex: "^X-Powered-By: PHP"}]
+  - name: cookie
+    matchers: [{type: cookie, name: phpsessid, regex: "^abc$"}]
+  - name: body
+    matchers: [{type: body, regex: 'href="[^"]+\.php'}]
+  - name: title
+    matchers: [{type: title, keywords: ["console"]}]
+  - name: meta
+    matchers: [{type: meta, name: generator, keywords: ["hugo"]}]
+  - name: script
+    matchers: [{type: script, keywords: [".chunk.js"]}]
+  - name: favicon
+    matchers: [{type: favicon, hashes: [42]}]
+  - name: miss
+    matchers: [{type: title, keywords: ["wordpress"]}]
+`)
+
+	got := matchNames(engine.Identify(testEvidence(), ScanRow{FaviconHash: 42, FaviconMD5: "x"}))
+	want := []string{"header", "header-any", "cookie", "body", "title", "meta", "script", "favicon"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	if got := engine.Identify(testEvidence(), ScanRow{}); len(got) != len(want)-1 {
+		t.Fatalf("expected the favicon rule to need a fetched favicon, got %v", got)
+	}
+}
+
+func TestFingerprintConditions(t *testing.T) {
+	engine := compileTestRules(t, `
+rules:
+  - name: any-rule
+    matchers:
+      - {type: title, keywords: ["nope"]}
+      - {type: header, name: x-powered-by, keywords: ["php"]}
+  - name: all-rule
+    condition: and
+    matchers:
+      - {type: title, keywords: ["admin"]}
+      - {type: header, name: x-powered-by, keywords: ["php"]}
+  - name: all-rule-miss
+    condition: and
+    matchers:
+      - {type: title, keywords: ["admin"]}
+      - {type: header, name: server, keywords: ["nginx"]}
+  - name: all-keywords
+    matchers: [{type: title, condition: and, keywords: ["admin", "console"]}]
+  - name: all-keywords-miss
+    matchers: [{type: title, condition: and, keywords: ["admin", "panel"]}]
+`)
+
+	got := matchNames(engine.Identify(testEvidence(), ScanRow{}))
+	want := []string{"any-rule", "all-rule", "all-keywords"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLoadFingerprintEngineMergesUserRules(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":    "rules:\n  - name: WordPress\n    disabled: true\n  - name: PHP\n    matchers: [{type: title, keywords: [\"admin\"]}]\n",
+		"b.json":    `{"rules": [{"name": "Custom CMS", "matchers": [{"type": "script", "keywords": ["app.chunk"]}]}]}`,
+		"notes.txt": "not a rule file",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+
+	engine, err := loadFingerprintEngine(splitRulePaths("\n" + dir + "\n# comment\n"))
+	if err != nil {
+		t.Fatalf("loadFingerprintEngine returned error: %v", err)
+	}
+
+	evidence := testEvidence()
+	evidence.body = "wp-content"
+	evidence.cookies = nil
+	evidence.headers.Del("X-Powered-By")
+	got := strings.Join(matchNames(engine.Identify(evidence, ScanRow{})), ",")
+	if strings.Contains(got, "WordPress") {
+		t.Fatalf("expected the disabled rule to be dropped, got %s", got)
+	}
+	if !strings.Contains(got, "PHP") || !strings.HasSuffix(got, "Custom CMS") {
+		t.Fatalf("expected the overridden PHP rule and the new rule, got %s", got)
+	}
+}
+
+func TestScanPlanCompilesFingerprintRulesOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<title>Acme Panel</title>"))
+	}))
+	defer server.Close()
+
+	rulesPath := filepath.Join(t.TempDir(), "rules.yaml")
+	rules := "rules:\n  - name: Acme\n    matchers: [{type: title, keywords: [\"acme\"]}]\n"
+	if err := os.WriteFile(rulesPath, []byte(rules), 0o644); err != nil {
+		t.Fatalf("write rules: %v", err)
+	}
+	plan, err := prepareScan(normalizeScanRequest(ScanRequest{
+		InputFilePath:        writeCLIInput(t, "200 "+server.URL+"/"),
+		Concurrency:          1,
+		DisableFavicon:       true,
+		FingerprintRulePaths: rulesPath,
+	}))
+	if err != nil {
+		t.Fatalf("prepare scan: %v", err)
+	}
+
+	// The workers use the engine compiled by the plan, not the file.
+	if err := os.Remove(rulesPath); err != nil {
+		t.Fatalf("remove rules: %v", err)
+	}
+	response, err := plan.run(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if len(response.Rows) != 1 || !slices.Contains(response.Rows[0].Components, "Acme") {
+		t.Fatalf("expected the plan's rules to identify the page, got %+v", response.Rows)
+	}
+}
+
+func TestCompileFingerprintRulesRejectsInvalidRules(t *testing.T) {
+	cases := map[string]string{
+		"no name":        "rules: [{matchers: [{type: body, keywords: [x]}]}]",
+		"no matchers":    "rules: [{name: a}]",
+		"unknown type":   "rules: [{name: a, matchers: [{type: status, keywords: [x]}]}]",
+		"bad condition":  "rules: [{name: a, condition: xor, matchers: [{type: body, keywords: [x]}]}]",
+		"bad regex":      "rules: [{name: a, matchers: [{type: body, regex: '('}]}]",
+		"empty matcher":  "rules: [{name: a, matchers: [{type: body}]}]",
+		"favicon hashes": "rules: [{name: a, matchers: [{type: favicon, keywords: [x]}]}]",
+	}
+	for name, source := range cases {
+		file, err := parseFingerprintFile([]byte(source))
+		if err != nil {
+			t.Fatalf("%s: parseFingerprintFile returned error: %v", name, err)
+		}
+		if _, err := compileFingerprintRules(file.Rules); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	if _, err := loadFingerprintEngine([]string{filepath.Join(t.TempDir(), "missing.yaml")}); err == nil {
+		t.Fatalf("expected a missing rule file to fail")
+	}
+}
+
+func TestDefaultFingerprintRulesCompile(t *testing.T) {
+	engine, err := defaultFingerprintEngine()
+	if err != nil {
+		t.Fatalf("default rules do not compile: %v", err)
+	}
+	if len(engine.rules) == 0 {
+		t.Fatalf("expected default rules")
+	}
+}
+
+func TestFingerprintConfidenceEvidenceAndImplies(t *testing.T) {
+	engine := compileTestRules(t, `
+rules:
+  - name: Weak
+    implies: ['Runtime\;confidence:50']
+    matchers:
+      - {type: title, keywords: ["admin"], confidence: 30}
+      - {type: body, keywords: ["index.php"], confidence: 40}
+      - {type: cookie, keywords: ["missing"], confidence: 50}
+  - name: Versioned
+    matchers:
+      - {type: meta, name: generator, regex: 'Hugo ([\d.]+)', version: '\1'}
+  - name: Runtime
+    categories: [Programming languages]
+`)
+
+	matches := engine.Identify(testEvidence(), ScanRow{})
+	if len(matches) != 3 {
+		t.Fatalf("expected two rule matches and one implied product, got %+v", matches)
+	}
+	if weak := matches[0]; weak.confidence != 70 || weak.evidence != "title: admin" {
+		t.Fatalf("expected summed confidence and the first evidence, got %+v", weak)
+	}
+	if versioned := matches[1]; versioned.version != "0.120" || versioned.confidence != 100 || versioned.evidence != "meta generator: Hugo 0.120" {
+		t.Fatalf("unexpected versioned match %+v", versioned)
+	}
+	if runtime := matches[2]; runtime.name != "Runtime" || runtime.confidence != 35 || runtime.evidence != "implied by Weak" || !reflect.DeepEqual(runtime.categories, []string{"Programming languages"}) {
+		t.Fatalf("unexpected implied match %+v", runtime)
+	}
+}
@@ -2,10 +2,42 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function CancelScan():Promise<void>;
+
+export function CancelScanJob(arg1:string):Promise<void>;
+
+export function DeleteAuthProfile(arg1:string):Promise<void>;
+
+export function EnqueueScan(arg1:main.ScanRequest):Promise<main.ScanJob>;
+
 export function Greet(arg1:string):Promise<string>;
 
+export function ListAuthProfiles():Promise<Array<main.AuthProfile>>;
+
+export function ListBrowserProfiles():Promise<Array<string>>;
+
+export function ListScanJobs():Promise<Array<main.ScanJob>>;
+
+export function PauseScanJob(arg1:string):Promise<void>;
+
+export function ResumeScanJob(arg1:string):Promise<void>;
+
 export function RunScan(arg1:main.ScanRequest):Promise<main.ScanResponse>;
 
+export function SaveAuthProfile(arg1:main.AuthProfile):Promise<void>;
+
+export function ScanJobResult(arg1:string):Promise<main.ScanResponse>;
+
+export function SelectCertificateFile():Promise<string>;
+
+export function SelectInputDirectory():Promise<string>;
+
 export function SelectInputFile():Promise<string>;
 
+export function SelectInputFiles():Promise<Array<string>>;
+
 export function SelectOutputDirectory():Promise<string>;
+
+export function SelectWordlistFile():Promise<string>;
+
+export function SetScanJobLimits(arg1:number,arg2:number):Promise<void>;
@@ -2,18 +2,82 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function CancelScan() {
+  return window['go']['main']['App']['CancelScan']();
+}
+
+export function CancelScanJob(arg1) {
+  return window['go']['main']['App']['CancelScanJob'](arg1);
+}
+
+export function DeleteAuthProfile(arg1) {
+  return window['go']['main']['App']['DeleteAuthProfile'](arg1);
+}
+
+export function EnqueueScan(arg1) {
+  return window['go']['main']['App']['EnqueueScan'](arg1);
+}
+
 export function Greet(arg1) {
   return window['go']['main']['App']['Greet'](arg1);
 }
 
+export function ListAuthProfiles() {
+  return window['go']['main']['App']['ListAuthProfiles']();
+}
+
+export function ListBrowserProfiles() {
+  return window['go']['main']['App']['ListBrowserProfiles']();
+}
+
+export function ListScanJobs() {
+  return window['go']['main']['App']['ListScanJobs']();
+}
+
+export function PauseScanJob(arg1) {
+  return window['go']['main']['App']['PauseScanJob'](arg1);
+}
+
+export function ResumeScanJob(arg1) {
+  return window['go']['main']['App']['ResumeScanJob'](arg1);
+}
+
 export function RunScan(arg1) {
   return window['go']['main']['App']['RunScan'](arg1);
 }
 
+export function SaveAuthProfile(arg1) {
+  return window['go']['main']['App']['SaveAuthProfile'](arg1);
+}
+
+export function ScanJobResult(arg1) {
+  return window['go']['main']['App']['ScanJobResult'](arg1);
+}
+
+export function SelectCertificateFile() {
+  return window['go']['main']['App']['SelectCertificateFile']();
+}
+
+export function SelectInputDirectory() {
+  return window['go']['main']['App']['SelectInputDirectory']();
+}
+
 export function SelectInputFile() {
   return window['go']['main']['App']['SelectInputFile']();
 }
 
+export function SelectInputFiles() {
+  return window['go']['main']['App']['SelectInputFiles']();
+}
+
 export function SelectOutputDirectory() {
   return window['go']['main']['App']['SelectOutputDirectory']();
 }
+
+export function SelectWordlistFile() {
+  return window['go']['main']['App']['SelectWordlistFile']();
+}
+
+export function SetScanJobLimits(arg1, arg2) {
+  return window['go']['main']['App']['SetScanJobLimits'](arg1, arg2);
+}
@@ -1,12 +1,135 @@
 export namespace main {
 	
+	export class AuthRule {
+	    hostPattern: string;
+	    headers: string;
+	    cookies: string;
+	    basicUser: string;
+	    basicPassword: string;
+	    bearerToken: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hostPattern = source["hostPattern"];
+	        this.headers = source["headers"];
+	        this.cookies = source["cookies"];
+	        this.basicUser = source["basicUser"];
+	        this.basicPassword = source["basicPassword"];
+	        this.bearerToken = source["bearerToken"];
+	    }
+	}
+	export class AuthProfile {
+	    name: string;
+	    rules: AuthRule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AuthProfile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.rules = this.convertValues(source["rules"], AuthRule);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Component {
+	    name: string;
+	    version: string;
+	    category: string;
+	    confidence: number;
+	    evidence: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Component(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.version = source["version"];
+	        this.category = source["category"];
+	        this.confidence = source["confidence"];
+	        this.evidence = source["evidence"];
+	    }
+	}
+	export class RedirectHop {
+	    url: string;
+	    statusCode: number;
+	    location: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RedirectHop(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.url = source["url"];
+	        this.statusCode = source["statusCode"];
+	        this.location = source["location"];
+	    }
+	}
 	export class ScanRequest {
 	    inputFilePath: string;
+	    inputFilePaths: string[];
+	    inputPattern: string;
+	    recursiveInput: boolean;
+	    reportPerSource: boolean;
 	    outputDir: string;
 	    concurrency: number;
 	    timeoutSeconds: number;
 	    followRedirect: boolean;
 	    deleteSourceAfterRun: boolean;
+	    includeStatus: string;
+	    excludeStatus: string;
+	    baseUrl: string;
+	    mode: string;
+	    wordlistPath: string;
+	    extensions: string;
+	    forceExtensions: boolean;
+	    excludeSizes: string;
+	    detectWildcard: boolean;
+	    dropWildcard: boolean;
+	    requestsPerSecond: number;
+	    perHostConcurrency: number;
+	    perHostDelayMs: number;
+	    perHostJitterMs: number;
+	    maxRetries: number;
+	    retryBackoffMs: number;
+	    proxyUrl: string;
+	    proxyList: string;
+	    replayProxyUrl: string;
+	    authRules: AuthRule[];
+	    authProfile: string;
+	    browserProfile: string;
+	    insecureSkipVerify: boolean;
+	    caBundlePath: string;
+	    clientCertPath: string;
+	    clientKeyPath: string;
+	    clientCertPassword: string;
+	    disableFavicon: boolean;
+	    fingerprintRulePaths: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ScanRequest(source);
@@ -15,18 +138,128 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.inputFilePath = source["inputFilePath"];
+	        this.inputFilePaths = source["inputFilePaths"];
+	        this.inputPattern = source["inputPattern"];
+	        this.recursiveInput = source["recursiveInput"];
+	        this.reportPerSource = source["reportPerSource"];
 	        this.outputDir = source["outputDir"];
 	        this.concurrency = source["concurrency"];
 	        this.timeoutSeconds = source["timeoutSeconds"];
 	        this.followRedirect = source["followRedirect"];
 	        this.deleteSourceAfterRun = source["deleteSourceAfterRun"];
+	        this.includeStatus = source["includeStatus"];
+	        this.excludeStatus = source["excludeStatus"];
+	        this.baseUrl = source["baseUrl"];
+	        this.mode = source["mode"];
+	        this.wordlistPath = source["wordlistPath"];
+	        this.extensions = source["extensions"];
+	        this.forceExtensions = source["forceExtensions"];
+	        this.excludeSizes = source["excludeSizes"];
+	        this.detectWildcard = source["detectWildcard"];
+	        this.dropWildcard = source["dropWildcard"];
+	        this.requestsPerSecond = source["requestsPerSecond"];
+	        this.perHostConcurrency = source["perHostConcurrency"];
+	        this.perHostDelayMs = source["perHostDelayMs"];
+	        this.perHostJitterMs = source["perHostJitterMs"];
+	        this.maxRetries = source["maxRetries"];
+	        this.retryBackoffMs = source["retryBackoffMs"];
+	        this.proxyUrl = source["proxyUrl"];
+	        this.proxyList = source["proxyList"];
+	        this.replayProxyUrl = source["replayProxyUrl"];
+	        this.authRules = this.convertValues(source["authRules"], AuthRule);
+	        this.authProfile = source["authProfile"];
+	        this.browserProfile = source["browserProfile"];
+	        this.insecureSkipVerify = source["insecureSkipVerify"];
+	        this.caBundlePath = source["caBundlePath"];
+	        this.clientCertPath = source["clientCertPath"];
+	        this.clientKeyPath = source["clientKeyPath"];
+	        this.clientCertPassword = source["clientCertPassword"];
+	        this.disableFavicon = source["disableFavicon"];
+	        this.fingerprintRulePaths = source["fingerprintRulePaths"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TLSInfo {
+	    version: string;
+	    cipherSuite: string;
+	    subject: string;
+	    issuer: string;
+	    sans: string[];
+	    notBefore: string;
+	    notAfter: string;
+	    keyType: string;
+	    fingerprint: string;
+	    expired: boolean;
+	    selfSigned: boolean;
+	    hostnameMismatch: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new TLSInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.cipherSuite = source["cipherSuite"];
+	        this.subject = source["subject"];
+	        this.issuer = source["issuer"];
+	        this.sans = source["sans"];
+	        this.notBefore = source["notBefore"];
+	        this.notAfter = source["notAfter"];
+	        this.keyType = source["keyType"];
+	        this.fingerprint = source["fingerprint"];
+	        this.expired = source["expired"];
+	        this.selfSigned = source["selfSigned"];
+	        this.hostnameMismatch = source["hostnameMismatch"];
 	    }
 	}
 	export class ScanRow {
 	    url: string;
+	    finalUrl: string;
+	    statusCode: number;
 	    title: string;
+	    contentType: string;
+	    contentLength: number;
+	    declaredLength: number;
+	    bodyTruncated: boolean;
+	    responseTimeMs: number;
+	    location: string;
+	    words: number;
+	    attempts: number;
+	    browserProfile: string;
 	    components: string[];
 	    error: string;
+	    sourceStatus: number;
+	    sourceSize: number;
+	    sourceRedirect: string;
+	    sourceFiles: string[];
+	    wildcardMatch: boolean;
+	    componentDetails: Component[];
+	    redirectChain: RedirectHop[];
+	    crossHostRedirect: boolean;
+	    offScopeRedirect: boolean;
+	    loginRedirect: boolean;
+	    tls?: TLSInfo;
+	    faviconUrl: string;
+	    faviconHash: number;
+	    faviconMd5: string;
 	
 	    static createFrom(source: any = {}) {
 	        return new ScanRow(source);
@@ -35,17 +268,89 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.url = source["url"];
+	        this.finalUrl = source["finalUrl"];
+	        this.statusCode = source["statusCode"];
 	        this.title = source["title"];
+	        this.contentType = source["contentType"];
+	        this.contentLength = source["contentLength"];
+	        this.declaredLength = source["declaredLength"];
+	        this.bodyTruncated = source["bodyTruncated"];
+	        this.responseTimeMs = source["responseTimeMs"];
+	        this.location = source["location"];
+	        this.words = source["words"];
+	        this.attempts = source["attempts"];
+	        this.browserProfile = source["browserProfile"];
 	        this.components = source["components"];
 	        this.error = source["error"];
+	        this.sourceStatus = source["sourceStatus"];
+	        this.sourceSize = source["sourceSize"];
+	        this.sourceRedirect = source["sourceRedirect"];
+	        this.sourceFiles = source["sourceFiles"];
+	        this.wildcardMatch = source["wildcardMatch"];
+	        this.componentDetails = this.convertValues(source["componentDetails"], Component);
+	        this.redirectChain = this.convertValues(source["redirectChain"], RedirectHop);
+	        this.crossHostRedirect = source["crossHostRedirect"];
+	        this.offScopeRedirect = source["offScopeRedirect"];
+	        this.loginRedirect = source["loginRedirect"];
+	        this.tls = this.convertValues(source["tls"], TLSInfo);
+	        this.faviconUrl = source["faviconUrl"];
+	        this.faviconHash = source["faviconHash"];
+	        this.faviconMd5 = source["faviconMd5"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class InputFileSummary {
+	    path: string;
+	    format: string;
+	    matchedLines: number;
+	    urls: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new InputFileSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.format = source["format"];
+	        this.matchedLines = source["matchedLines"];
+	        this.urls = source["urls"];
 	    }
 	}
 	export class ScanResponse {
 	    reportPath: string;
-	    total200Lines: number;
+	    reportPaths: string[];
+	    mode: string;
+	    wordlist: string;
+	    inputFormat: string;
+	    inputFiles: InputFileSummary[];
+	    skippedFiles: string[];
+	    statusFilter: string;
+	    totalMatchedLines: number;
 	    totalUrls: number;
 	    succeeded: number;
 	    failed: number;
+	    wildcardMatches: number;
+	    replayed: number;
+	    sanPivots: string[];
+	    cancelled: boolean;
 	    rows: ScanRow[];
 	
 	    static createFrom(source: any = {}) {
@@ -55,10 +360,21 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.reportPath = source["reportPath"];
-	        this.total200Lines = source["total200Lines"];
+	        this.reportPaths = source["reportPaths"];
+	        this.mode = source["mode"];
+	        this.wordlist = source["wordlist"];
+	        this.inputFormat = source["inputFormat"];
+	        this.inputFiles = this.convertValues(source["inputFiles"], InputFileSummary);
+	        this.skippedFiles = source["skippedFiles"];
+	        this.statusFilter = source["statusFilter"];
+	        this.totalMatchedLines = source["totalMatchedLines"];
 	        this.totalUrls = source["totalUrls"];
 	        this.succeeded = source["succeeded"];
 	        this.failed = source["failed"];
+	        this.wildcardMatches = source["wildcardMatches"];
+	        this.replayed = source["replayed"];
+	        this.sanPivots = source["sanPivots"];
+	        this.cancelled = source["cancelled"];
 	        this.rows = this.convertValues(source["rows"], ScanRow);
 	    }
 	
@@ -80,6 +396,77 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class ScanProgress {
+	    done: number;
+	    total: number;
+	    succeeded: number;
+	    failed: number;
+	    ratePerSecond: number;
+	    etaSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanProgress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.done = source["done"];
+	        this.total = source["total"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.ratePerSecond = source["ratePerSecond"];
+	        this.etaSeconds = source["etaSeconds"];
+	    }
+	}
+	export class ScanJob {
+	    id: string;
+	    status: string;
+	    cancelledBeforeStart: boolean;
+	    inputFile: string;
+	    mode: string;
+	    progress: ScanProgress;
+	    error: string;
+	    reportPath: string;
+	    createdAt: string;
+	    startedAt: string;
+	    finishedAt: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ScanJob(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.status = source["status"];
+	        this.cancelledBeforeStart = source["cancelledBeforeStart"];
+	        this.inputFile = source["inputFile"];
+	        this.mode = source["mode"];
+	        this.progress = this.convertValues(source["progress"], ScanProgress);
+	        this.error = source["error"];
+	        this.reportPath = source["reportPath"];
+	        this.createdAt = source["createdAt"];
+	        this.startedAt = source["startedAt"];
+	        this.finishedAt = source["finishedAt"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 
 }
-
@@ -5,6 +5,8 @@ go 1.23
 require (
 	github.com/wailsapp/wails/v2 v2.11.0
 	golang.org/x/net v0.35.0
+	gopkg.in/yaml.v3 v3.0.1
+	software.sslmate.com/src/go-pkcs12 v0.7.3
 )
 
 require (
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	inputFormatText = "text"
+
+	inputSniffSize  = 64 << 10
+	inputSniffLines = 20
+)
+
+var (
+	statusLineRegex = regexp.MustCompile(`^\s*(\d{3})\b`)
+	urlRegex        = regexp.MustCompile(`https?://[^\s"'<>]+`)
+	sizeRegex       = regexp.MustCompile(`(?i)\b(\d+(?:\.\d+)?)\s*(B|KB|MB|GB)\b`)
+	ansiEscapeRegex = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+	utf8BOM         = []byte{0xEF, 0xBB, 0xBF}
+)
+
+// inputParser reads the report format of one tool. Sniff only sees the first
+// inputSniffSize bytes and must be cheap; parsers are tried in the order of
+// inputParsers and the first match wins.
+type inputParser interface {
+	Format() string
+	Sniff(head []byte) bool
+	Parse(data []byte, collector *inputCollector) error
+}
+
+// lineParser is implemented by formats that carry one record per line, so
+// they can be read with parseLines.
+type lineParser interface {
+	ParseLine(line string, options inputOptions) (inputEntry, bool)
+}
+
+var inputParsers = []inputParser{
+	ffufJSONParser{},
+	feroxbusterJSONParser{},
+	httpxJSONParser{},
+	dirsearchJSONParser{},
+	dirsearchXMLParser{},
+	dirsearchCSVParser{},
+	dirsearchMarkdownParser{},
+	feroxbusterTextParser{},
+	gobusterTextParser{},
+	httpxTextParser{},
+	dirsearchPlainParser{},
+}
+
+// inputOptions controls how input reports are interpreted. BaseURL resolves
+// tools that only print paths, such as gobuster without --expanded.
+type inputOptions struct {
+	Filter  statusFilter
+	BaseURL string
+}
+
+// inputEntry is a URL taken from an input report together with what the
+// source tool recorded about it. Size is -1 when the source did not say.
+type inputEntry struct {
+	URL      string
+	Status   int
+	Size     int64
+	Redirect string
+
+	// Sources lists the input files of a batch scan the URL appeared in.
+	Sources []string
+}
+
+type inputParseResult struct {
+	Format       string
+	Entries      []inputEntry
+	MatchedLines int
+}
+
+func urlEntry(url string) inputEntry {
+	return inputEntry{URL: url, Size: -1}
+}
+
+// inputCollector applies the status filter and de-duplicates URLs in the
+// order they first appear. Records without a status, such as httpx output
+// without -sc, are kept since the scan probes them anyway. MatchedLines counts
+// every record accepted by the filter, including records without a usable URL.
+type inputCollector struct {
+	options inputOptions
+	seen    map[string]struct{}
+	result  inputParseResult
+
+	// emit, when set, receives every accepted entry instead of
+	// result.Entries.
+	emit func(inputEntry)
+}
+
+func newInputCollector(format string, options inputOptions) *inputCollector {
+	return &inputCollector{
+		options: options,
+		seen:    make(map[string]struct{}),
+		result:  inputParseResult{Format: format, Entries: make([]inputEntry, 0)},
+	}
+}
+
+func (c *inputCollector) add(entry inputEntry) {
+	if entry.Status != 0 && !c.options.Filter.Match(entry.Status) {
+		return
+	}
+
+	c.result.MatchedLines++
+	entry.URL = strings.TrimSpace(entry.URL)
+	if entry.URL == "" {
+		return
+	}
+	if _, ok := c.seen[entry.URL]; ok {
+		return
+	}
+
+	c.seen[entry.URL] = struct{}{}
+	if c.emit != nil {
+		c.emit(entry)
+		return
+	}
+	c.result.Entries = append(c.result.Entries, entry)
+}
+
+// parseInputFile sniffs which tool produced the file (dirsearch, ffuf,
+// feroxbuster, gobuster or httpx) and extracts the URLs whose status is
+// accepted by the filter. Unrecognised files are read line by line.
+func parseInputFile(path string, options inputOptions) (inputParseResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return inputParseResult{}, fmt.Errorf("open input file: %w", err)
+	}
+	data = bytes.TrimPrefix(data, utf8BOM)
+
+	parser := detectInputParser(data)
+	collector := newInputCollector(parser.Format(), options)
+	if err := parser.Parse(data, collector); err != nil {
+		return inputParseResult{}, fmt.Errorf("parse %s input: %w", parser.Format(), err)
+	}
+
+	return collector.result, nil
+}
+
+// streamInput is parseInputFile for a stream such as stdin. The format is
+// sniffed from the first inputSniffLines lines; line formats are then parsed
+// as lines arrive and every accepted entry is sent on entries straight away,
+// while formats that need the whole document are read to the end first. The
+// returned result has no Entries; the number of entries sent is returned
+// instead. entries is closed on return, and reading stops early when ctx is
+// cancelled.
+func streamInput(ctx context.Context, r io.Reader, options inputOptions, entries chan<- inputEntry) (inputParseResult, int, error) {
+	defer close(entries)
+
+	reader := bufio.NewReader(r)
+	head := make([]byte, 0, 4096)
+	for lines := 0; lines < inputSniffLines && len(head) < inputSniffSize; {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return inputParseResult{}, 0, fmt.Errorf("read input: %w", err)
+		}
+		head = append(head, line...)
+		if text := bytes.TrimSpace(line); len(text) > 0 {
+			lines++
+			if streamFormatSettled(head, text) {
+				break
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	head = bytes.TrimPrefix(head, utf8BOM)
+
+	parser := detectInputParser(head)
+	collector := newInputCollector(parser.Format(), options)
+	collector.emit = func(entry inputEntry) {
+		select {
+		case entries <- entry:
+		case <-ctx.Done():
+		}
+	}
+
+	if lines, ok := parser.(lineParser); ok {
+		if err := scanLines(ctx, newLineScanner(io.MultiReader(bytes.NewReader(head), reader)), lines, collector); err != nil {
+			return inputParseResult{}, 0, err
+		}
+		return collector.result, len(collector.seen), nil
+	}
+
+	rest, err := io.ReadAll(reader)
+	if err != nil {
+		return inputParseResult{}, 0, fmt.Errorf("read input: %w", err)
+	}
+	data := append(head, rest...)
+	if err := parser.Parse(data, collector); err != nil {
+		return inputParseResult{}, 0, fmt.Errorf("parse %s input: %w", parser.Format(), err)
+	}
+	return collector.result, len(collector.seen), nil
+}
+
+// streamFormatSettled reports whether head, ending in line, is enough to pick
+// a line format, so a slow pipe does not hold the scan back until
+// inputSniffLines lines have arrived. Document formats are read whole anyway
+// and keep sniffing.
+func streamFormatSettled(head, line []byte) bool {
+	switch detectInputParser(bytes.TrimPrefix(head, utf8BOM)).(type) {
+	case textLineParser:
+		return statusLineRegex.Match(line)
+	case lineParser:
+		return true
+	}
+	return false
+}
+
+func detectInputParser(data []byte) inputParser {
+	head := data
+	if len(head) > inputSniffSize {
+		head = head[:inputSniffSize]
+	}
+
+	for _, parser := range inputParsers {
+		if parser.Sniff(head) {
+			return parser
+		}
+	}
+	return textLineParser{}
+}
+
+// textLineParser handles any log whose lines start with a status code followed
+// somewhere by a URL. It is the fallback when no tool format is recognised.
+type textLineParser struct{}
+
+func (textLineParser) Format() string { return inputFormatText }
+
+func (textLineParser) Sniff([]byte) bool { return true }
+
+func (p textLineParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (textLineParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	return parseStatusLine(line)
+}
+
+func parseStatusLine(line string) (inputEntry, bool) {
+	statusMatch := statusLineRegex.FindStringSubmatch(line)
+	if statusMatch == nil {
+		return inputEntry{}, false
+	}
+
+	entry := urlEntry(urlRegex.FindString(line))
+	entry.Status, _ = strconv.Atoi(statusMatch[1])
+	if sizeMatch := sizeRegex.FindStringSubmatch(line); sizeMatch != nil {
+		entry.Size = parseHumanSize(sizeMatch[1], sizeMatch[2])
+	}
+	if redirectMatch := redirectArrowRegex.FindStringSubmatch(line); redirectMatch != nil {
+		entry.Redirect = redirectMatch[1]
+	}
+
+	return entry, true
+}
+
+func parseLines(data []byte, parser lineParser, collector *inputCollector) error {
+	return scanLines(context.Background(), newLineScanner(bytes.NewReader(data)), parser, collector)
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 1024), 1024*1024)
+	return scanner
+}
+
+func scanLines(ctx context.Context, scanner *bufio.Scanner, parser lineParser, collector *inputCollector) error {
+	for ctx.Err() == nil && scanner.Scan() {
+		entry, ok := parser.ParseLine(scanner.Text(), collector.options)
+		if !ok {
+			continue
+		}
+		collector.add(entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read input file: %w", err)
+	}
+	return nil
+}
+
+// headLines returns up to inputSniffLines non-empty lines from head, with
+// terminal colour codes removed.
+func headLines(head []byte) []string {
+	lines := make([]string, 0, inputSniffLines)
+	for _, line := range strings.Split(string(head), "\n") {
+		line = strings.TrimSpace(stripANSI(line))
+		if line == "" {
+			continue
+		}
+		lines = append(lines, line)
+		if len(lines) == inputSniffLines {
+			break
+		}
+	}
+	return lines
+}
+
+// firstJSONLine decodes the first non-empty line of head as a JSON object. It
+// is used to tell the JSON-lines formats apart.
+func firstJSONLine(head []byte) (map[string]json.RawMessage, bool) {
+	lines := headLines(head)
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "{") {
+		return nil, false
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(lines[0]), &object); err != nil {
+		return nil, false
+	}
+	return object, true
+}
+
+func anyLineMatches(head []byte, pattern *regexp.Regexp) bool {
+	for _, line := range headLines(head) {
+		if pattern.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+func stripANSI(value string) string {
+	return ansiEscapeRegex.ReplaceAllString(value, "")
+}
+
+func joinTargetPath(target, path string) string {
+	if target == "" || path == "" {
+		return ""
+	}
+	return strings.TrimSuffix(target, "/") + "/" + strings.TrimPrefix(path, "/")
+}
+
+// parseSize accepts plain byte counts as well as dirsearch's "12KB" notation.
+// It returns -1 when value is empty or unparsable.
+func parseSize(value string) int64 {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return -1
+	}
+	if size, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return size
+	}
+	if match := sizeRegex.FindStringSubmatch(value); match != nil {
+		return parseHumanSize(match[1], match[2])
+	}
+	return -1
+}
+
+func parseHumanSize(number, unit string) int64 {
+	value, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return -1
+	}
+
+	switch strings.ToUpper(unit) {
+	case "KB":
+		value *= 1 << 10
+	case "MB":
+		value *= 1 << 20
+	case "GB":
+		value *= 1 << 30
+	}
+	return int64(value)
+}
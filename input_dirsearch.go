@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	inputFormatDirsearchPlain = "dirsearch-plain"
+	inputFormatDirsearchJSON  = "dirsearch-json"
+	inputFormatDirsearchCSV   = "dirsearch-csv"
+	inputFormatDirsearchXML   = "dirsearch-xml"
+	inputFormatDirsearchMD    = "dirsearch-md"
+)
+
+var redirectArrowRegex = regexp.MustCompile(`->\s*REDIRECTS TO:\s*(\S+)`)
+
+// dirsearchPlainParser reads `--format plain` reports, recognised by the
+// "# Dirsearch started" banner.
+type dirsearchPlainParser struct{}
+
+func (dirsearchPlainParser) Format() string { return inputFormatDirsearchPlain }
+
+func (dirsearchPlainParser) Sniff(head []byte) bool {
+	lines := headLines(head)
+	return len(lines) > 0 && strings.HasPrefix(strings.ToLower(lines[0]), "# dirsearch started")
+}
+
+func (p dirsearchPlainParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (dirsearchPlainParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	return parseStatusLine(line)
+}
+
+type dirsearchJSONParser struct{}
+
+type dirsearchJSONResult struct {
+	URL           string `json:"url"`
+	Path          string `json:"path"`
+	Status        int    `json:"status"`
+	ContentLength *int64 `json:"content-length"`
+	Redirect      string `json:"redirect"`
+}
+
+func (dirsearchJSONParser) Format() string { return inputFormatDirsearchJSON }
+
+func (dirsearchJSONParser) Sniff(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{"))
+}
+
+// Parse understands both the current {"results": [...]} layout and the legacy
+// layout keyed by target URL with relative paths.
+func (dirsearchJSONParser) Parse(data []byte, collector *inputCollector) error {
+	var document map[string]json.RawMessage
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	if raw, ok := document["results"]; ok {
+		var results []dirsearchJSONResult
+		if err := json.Unmarshal(raw, &results); err != nil {
+			return err
+		}
+		for _, result := range results {
+			collector.add(result.entry(""))
+		}
+		return nil
+	}
+
+	targets := make([]string, 0, len(document))
+	for target := range document {
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			targets = append(targets, target)
+		}
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		var results []dirsearchJSONResult
+		if err := json.Unmarshal(document[target], &results); err != nil {
+			return err
+		}
+		for _, result := range results {
+			collector.add(result.entry(target))
+		}
+	}
+
+	return nil
+}
+
+func (r dirsearchJSONResult) entry(target string) inputEntry {
+	entry := urlEntry(r.URL)
+	if entry.URL == "" {
+		entry.URL = joinTargetPath(target, r.Path)
+	}
+	entry.Status = r.Status
+	entry.Redirect = r.Redirect
+	if r.ContentLength != nil {
+		entry.Size = *r.ContentLength
+	}
+	return entry
+}
+
+type dirsearchCSVParser struct{}
+
+func (dirsearchCSVParser) Format() string { return inputFormatDirsearchCSV }
+
+func (dirsearchCSVParser) Sniff(head []byte) bool {
+	lines := headLines(head)
+	if len(lines) == 0 {
+		return false
+	}
+	firstLine := strings.ToLower(lines[0])
+	return strings.HasPrefix(firstLine, "url,status") || strings.HasPrefix(firstLine, "time,url,status")
+}
+
+func (dirsearchCSVParser) Parse(data []byte, collector *inputCollector) error {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return err
+	}
+	columns := indexColumns(header)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		collector.add(columns.entry(record, ""))
+	}
+}
+
+type dirsearchXMLParser struct{}
+
+type dirsearchXMLDocument struct {
+	Targets []struct {
+		URL           string `xml:"url,attr"`
+		Status        string `xml:"status"`
+		ContentLength string `xml:"contentLength"`
+		Redirect      string `xml:"redirect"`
+		Paths         []struct {
+			Path          string `xml:"path,attr"`
+			Status        string `xml:"status"`
+			ContentLength string `xml:"contentLength"`
+			Redirect      string `xml:"redirect"`
+		} `xml:"info"`
+	} `xml:"target"`
+}
+
+func (dirsearchXMLParser) Format() string { return inputFormatDirsearchXML }
+
+func (dirsearchXMLParser) Sniff(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("<"))
+}
+
+// Parse accepts one <target url=...> element per hit, or the legacy layout
+// where each target wraps <info path=...> elements.
+func (dirsearchXMLParser) Parse(data []byte, collector *inputCollector) error {
+	var document dirsearchXMLDocument
+	if err := xml.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	for _, target := range document.Targets {
+		if len(target.Paths) == 0 {
+			collector.add(xmlEntry(target.URL, target.Status, target.ContentLength, target.Redirect))
+			continue
+		}
+		for _, path := range target.Paths {
+			collector.add(xmlEntry(joinTargetPath(target.URL, path.Path), path.Status, path.ContentLength, path.Redirect))
+		}
+	}
+
+	return nil
+}
+
+func xmlEntry(url, status, contentLength, redirect string) inputEntry {
+	entry := urlEntry(url)
+	entry.Status, _ = strconv.Atoi(strings.TrimSpace(status))
+	entry.Size = parseSize(contentLength)
+	entry.Redirect = strings.TrimSpace(redirect)
+	return entry
+}
+
+type dirsearchMarkdownParser struct{}
+
+func (dirsearchMarkdownParser) Format() string { return inputFormatDirsearchMD }
+
+func (dirsearchMarkdownParser) Sniff(head []byte) bool {
+	for _, line := range headLines(head) {
+		cells := splitMarkdownRow(line)
+		if len(cells) >= 2 && strings.EqualFold(cells[1], "status") {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse reads the pipe tables of a dirsearch md report. Legacy reports list
+// relative paths under a "### Target: <url>" heading.
+func (dirsearchMarkdownParser) Parse(data []byte, collector *inputCollector) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 1024), 1024*1024)
+
+	var columns csvColumns
+	target := ""
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if heading, ok := strings.CutPrefix(line, "### Target:"); ok {
+			target = strings.TrimSpace(heading)
+			continue
+		}
+
+		cells := splitMarkdownRow(line)
+		if len(cells) < 2 {
+			continue
+		}
+		if strings.EqualFold(cells[1], "status") {
+			columns = indexColumns(cells)
+			continue
+		}
+		if columns == nil || strings.HasPrefix(cells[0], "---") {
+			continue
+		}
+		collector.add(columns.entry(cells, target))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read input file: %w", err)
+	}
+	return nil
+}
+
+func splitMarkdownRow(line string) []string {
+	line = strings.TrimSpace(line)
+	if !strings.Contains(line, "|") {
+		return nil
+	}
+
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i := range cells {
+		cells[i] = strings.TrimSpace(cells[i])
+	}
+	return cells
+}
+
+// csvColumns maps lower-cased header names of dirsearch csv and md tables to
+// their column index.
+type csvColumns map[string]int
+
+func indexColumns(header []string) csvColumns {
+	columns := make(csvColumns, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+func (c csvColumns) value(record []string, names ...string) string {
+	for _, name := range names {
+		if index, ok := c[name]; ok && index < len(record) {
+			return strings.TrimSpace(record[index])
+		}
+	}
+	return ""
+}
+
+func (c csvColumns) entry(record []string, target string) inputEntry {
+	entry := urlEntry(c.value(record, "url"))
+	if entry.URL == "" {
+		entry.URL = joinTargetPath(target, c.value(record, "path"))
+	}
+	entry.Status, _ = strconv.Atoi(c.value(record, "status"))
+	entry.Size = parseSize(c.value(record, "size", "content length", "content-length"))
+	entry.Redirect = c.value(record, "redirection", "redirect")
+	return entry
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseInputFileDirsearchStyle(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.txt")
+
+	content := strings.Join([]string{
+		"200 123B 0.001s http://example.com/a",
+		"301 123B 0.001s http://example.com/b",
+		"403 123B 0.001s https://example.org/blocked",
+		"404 123B 0.001s http://example.com/ignore",
+		"  200 88B 0.003s https://example.org/login?x=1",
+		"403 no-url-here",
+		"301 duplicate http://example.com/b",
+	}, "\n")
+
+	if err := os.WriteFile(inputPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input file: %v", err)
+	}
+
+	result, err := parseInputFile(inputPath, inputOptions{Filter: defaultStatusFilter()})
+	if err != nil {
+		t.Fatalf("parse input file: %v", err)
+	}
+
+	if result.MatchedLines != 6 {
+		t.Fatalf("expected total matched lines=6, got %d", result.MatchedLines)
+	}
+	if result.Format != inputFormatText {
+		t.Fatalf("expected text format, got %s", result.Format)
+	}
+	urls := entryURLs(result.Entries)
+
+	expected := []string{
+		"http://example.com/a",
+		"http://example.com/b",
+		"https://example.org/blocked",
+		"https://example.org/login?x=1",
+	}
+	if len(urls) != len(expected) {
+		t.Fatalf("expected %d urls, got %d: %#v", len(expected), len(urls), urls)
+	}
+
+	for i := range expected {
+		if urls[i] != expected[i] {
+			t.Fatalf("expected url[%d]=%s, got %s", i, expected[i], urls[i])
+		}
+	}
+}
+
+func TestParseInputFileHonorsStatusFilter(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "input.txt")
+
+	content := strings.Join([]string{
+		"200 123B 0.001s http://example.com/ok",
+		"204 0B 0.001s http://example.com/empty",
+		"302 0B 0.001s http://example.com/login",
+		"401 12B 0.001s http://example.com/admin",
+		"500 12B 0.001s http://example.com/crash",
+	}, "\n")
+	if err := os.WriteFile(inputPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input file: %v", err)
+	}
+
+	filter, err := parseStatusFilter("2xx,302,401", "204")
+	if err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+
+	result, err := parseInputFile(inputPath, inputOptions{Filter: filter})
+	if err != nil {
+		t.Fatalf("parse input file: %v", err)
+	}
+	urls, totalMatched := entryURLs(result.Entries), result.MatchedLines
+
+	expected := []string{"http://example.com/ok", "http://example.com/login", "http://example.com/admin"}
+	if totalMatched != len(expected) || strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v (%d lines), got %v (%d lines)", expected, len(expected), urls, totalMatched)
+	}
+}
+
+func TestParseInputFileKeepsHttpxEntriesWithoutStatus(t *testing.T) {
+	tempDir := t.TempDir()
+	inputPath := filepath.Join(tempDir, "httpx.txt")
+
+	content := strings.Join([]string{
+		"http://example.com/a [Home]",
+		"http://example.com/b [nginx] [text/html]",
+		"http://example.com/c [404] [Missing]",
+	}, "\n")
+	if err := os.WriteFile(inputPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("write input file: %v", err)
+	}
+
+	result, err := parseInputFile(inputPath, inputOptions{Filter: defaultStatusFilter()})
+	if err != nil {
+		t.Fatalf("parse input file: %v", err)
+	}
+	if result.Format != "httpx-text" {
+		t.Fatalf("expected httpx-text, got %q", result.Format)
+	}
+
+	expected := []string{"http://example.com/a", "http://example.com/b"}
+	if urls := entryURLs(result.Entries); strings.Join(urls, " ") != strings.Join(expected, " ") {
+		t.Fatalf("expected %v, got %v", expected, urls)
+	}
+	for _, entry := range result.Entries {
+		if entry.Status != 0 {
+			t.Fatalf("expected no status for %s, got %d", entry.URL, entry.Status)
+		}
+	}
+}
+
+func TestParseInputFileDetectsDirsearchFormats(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		format  string
+	}{
+		{
+			name:   "plain",
+			format: inputFormatDirsearchPlain,
+			content: strings.Join([]string{
+				"# Dirsearch started Mon Jan  1 00:00:00 2024 as: dirsearch.py -u http://example.com",
+				"",
+				"200   571B  http://example.com/index.php",
+				"301   169B  http://example.com/admin    -> REDIRECTS TO: http://example.com/admin/",
+				"404     9B  http://example.com/missing",
+			}, "\n"),
+		},
+		{
+			name:   "json",
+			format: inputFormatDirsearchJSON,
+			content: `{"info": {"args": "-u http://example.com", "time": "now"}, "results": [
+				{"url": "http://example.com/index.php", "status": 200, "content-length": 571, "content-type": "text/html", "redirect": ""},
+				{"url": "http://example.com/admin", "status": 301, "content-length": 169, "content-type": "text/html", "redirect": "http://example.com/admin/"},
+				{"url": "http://example.com/missing", "status": 404, "content-length": 9, "content-type": "text/html", "redirect": ""}
+			]}`,
+		},
+		{
+			name:   "legacy json",
+			format: inputFormatDirsearchJSON,
+			content: `{"time": "now", "http://example.com:80/": [
+				{"status": 200, "path": "/index.php", "content-length": 571, "redirect": null},
+				{"status": 301, "path": "/admin", "content-length": 169, "redirect": "http://example.com/admin/"}
+			]}`,
+		},
+		{
+			name:   "csv",
+			format: inputFormatDirsearchCSV,
+			content: strings.Join([]string{
+				"URL,Status,Size,Content Type,Redirection",
+				"http://example.com/index.php,200,571,text/html,",
+				"http://example.com/admin,301,169,text/html,http://example.com/admin/",
+			}, "\n"),
+		},
+		{
+			name:   "xml",
+			format: inputFormatDirsearchXML,
+			content: `<?xml version="1.0" ?>
+<dirsearchscan args="-u http://example.com" time="now">
+  <target url="http://example.com/index.php"><status>200</status><contentLength>571</contentLength><contentType>text/html</contentType><redirect></redirect></target>
+  <target url="http://example.com/admin"><status>301</status><contentLength>169</contentLength><contentType>text/html</contentType><redirect>http://example.com/admin/</redirect></target>
+</dirsearchscan>`,
+		},
+		{
+			name:   "markdown",
+			format: inputFormatDirsearchMD,
+			content: strings.Join([]string{
+				"### Info",
+				"Args: -u http://example.com",
+				"Time: now",
+				"",
+				"URL | Status | Size | Content Type | Redirection",
+				"----|--------|------|--------------|------------",
+				"http://example.com/index.php | 200 | 571 | text/html | ",
+				"http://example.com/admin | 301 | 169 | text/html | http://example.com/admin/",
+			}, "\n"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputPath := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(inputPath, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("write input file: %v", err)
+			}
+
+			result, err := parseInputFile(inputPath, inputOptions{Filter: defaultStatusFilter()})
+			if err != nil {
+				t.Fatalf("parse input file: %v", err)
+			}
+			if result.Format != tc.format {
+				t.Fatalf("expected format %s, got %s", tc.format, result.Format)
+			}
+			if len(result.Entries) != 2 || result.MatchedLines != 2 {
+				t.Fatalf("expected 2 entries, got %+v", result)
+			}
+
+			first, second := result.Entries[0], result.Entries[1]
+			if !strings.HasSuffix(first.URL, "/index.php") || first.Status != 200 || first.Size != 571 || first.Redirect != "" {
+				t.Fatalf("unexpected first entry %+v", first)
+			}
+			if !strings.HasSuffix(second.URL, "/admin") || second.Status != 301 || second.Size != 169 || second.Redirect != "http://example.com/admin/" {
+				t.Fatalf("unexpected second entry %+v", second)
+			}
+		})
+	}
+}
+
+func TestParseInputFileDetectsToolFormats(t *testing.T) {
+	cases := []struct {
+		name    string
+		content string
+		format  string
+	}{
+		{
+			name:   "ffuf json",
+			format: inputFormatFfufJSON,
+			content: `{"commandline":"ffuf -u http://example.com/FUZZ -w words.txt -of json","time":"now","results":[` +
+				`{"input":{"FUZZ":"index.php"},"position":1,"status":200,"length":571,"words":10,"lines":5,"content-type":"text/html","redirectlocation":"","url":"http://example.com/index.php","host":"example.com"},` +
+				`{"input":{"FUZZ":"admin"},"position":2,"status":301,"length":169,"words":5,"lines":7,"content-type":"text/html","redirectlocation":"http://example.com/admin/","url":"http://example.com/admin","host":"example.com"}]}`,
+		},
+		{
+			name:   "feroxbuster json",
+			format: inputFormatFeroxbusterJSON,
+			content: strings.Join([]string{
+				`{"type":"configuration","target_url":"http://example.com"}`,
+				`{"type":"response","url":"http://example.com/index.php","original_url":"http://example.com","path":"/index.php","wildcard":false,"status":200,"method":"GET","content_length":571,"line_count":5,"word_count":10,"headers":{"content-type":"text/html"}}`,
+				`{"type":"response","url":"http://example.com/admin","original_url":"http://example.com","path":"/admin","wildcard":false,"status":301,"method":"GET","content_length":169,"line_count":7,"word_count":5,"headers":{"location":"http://example.com/admin/"}}`,
+				`{"type":"statistics","requests":100}`,
+			}, "\n"),
+		},
+		{
+			name:   "feroxbuster text",
+			format: inputFormatFeroxbusterText,
+			content: strings.Join([]string{
+				"200      GET        5l       10w      571c http://example.com/index.php",
+				"301      GET        7l        5w      169c http://example.com/admin => http://example.com/admin/",
+				"404      GET        1l        2w        9c http://example.com/missing",
+			}, "\n"),
+		},
+		{
+			name:   "gobuster text",
+			format: inputFormatGobusterText,
+			content: strings.Join([]string{
+				"/index.php            (Status: 200) [Size: 571]",
+				"/admin                (Status: 301) [Size: 169] [--> http://example.com/admin/]",
+				"/missing              (Status: 404) [Size: 9]",
+			}, "\n"),
+		},
+		{
+			name:   "httpx json",
+			format: inputFormatHttpxJSON,
+			content: strings.Join([]string{
+				`{"timestamp":"now","url":"http://example.com/index.php","input":"example.com/index.php","title":"Home","status_code":200,"content_length":571,"webserver":"nginx"}`,
+				`{"timestamp":"now","url":"http://example.com/admin","input":"example.com/admin","status_code":301,"content_length":169,"location":"http://example.com/admin/"}`,
+			}, "\n"),
+		},
+		{
+			name:   "httpx text",
+			format: inputFormatHttpxText,
+			content: strings.Join([]string{
+				"http://example.com/index.php [\x1b[32m200\x1b[0m] [571] [Home]",
+				"http://example.com/admin [301] [169] [http://example.com/admin/]",
+			}, "\n"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			inputPath := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(inputPath, []byte(tc.content), 0o644); err != nil {
+				t.Fatalf("write input file: %v", err)
+			}
+
+			result, err := parseInputFile(inputPath, inputOptions{Filter: defaultStatusFilter(), BaseURL: "http://example.com/"})
+			if err != nil {
+				t.Fatalf("parse input file: %v", err)
+			}
+			if result.Format != tc.format {
+				t.Fatalf("expected format %s, got %s", tc.format, result.Format)
+			}
+
+			expected := []inputEntry{
+				{URL: "http://example.com/index.php", Status: 200, Size: 571},
+				{URL: "http://example.com/admin", Status: 301, Size: 169, Redirect: "http://example.com/admin/"},
+			}
+			if len(result.Entries) != len(expected) {
+				t.Fatalf("expected %d entries, got %+v", len(expected), result.Entries)
+			}
+			for i := range expected {
+				if !reflect.DeepEqual(result.Entries[i], expected[i]) {
+					t.Fatalf("expected entry %+v, got %+v", expected[i], result.Entries[i])
+				}
+			}
+		})
+	}
+}
+
+func entryURLs(entries []inputEntry) []string {
+	urls := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		urls = append(urls, entry.URL)
+	}
+	return urls
+}
+
+func TestStreamInputSendsLineEntriesBeforeEOF(t *testing.T) {
+	reader, writer := io.Pipe()
+	entries := make(chan inputEntry)
+	type streamResult struct {
+		result inputParseResult
+		urls   int
+		err    error
+	}
+	done := make(chan streamResult, 1)
+	go func() {
+		result, urls, err := streamInput(context.Background(), reader, inputOptions{Filter: defaultStatusFilter()}, entries)
+		done <- streamResult{result, urls, err}
+	}()
+
+	_, _ = io.WriteString(writer, "200 571B http://example.com/index.php\n")
+	select {
+	case entry := <-entries:
+		if entry.URL != "http://example.com/index.php" || entry.Status != 200 || entry.Size != 571 {
+			t.Fatalf("unexpected first entry %+v", entry)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the first entry before the input ended")
+	}
+
+	go func() {
+		_, _ = io.WriteString(writer, "404 9B http://example.com/missing\n301 http://example.com/admin\n200 http://example.com/index.php\n")
+		writer.Close()
+	}()
+	rest := make([]inputEntry, 0)
+	for entry := range entries {
+		rest = append(rest, entry)
+	}
+
+	result := <-done
+	if result.err != nil {
+		t.Fatalf("streamInput returned error: %v", result.err)
+	}
+	if len(rest) != 1 || rest[0].URL != "http://example.com/admin" {
+		t.Fatalf("expected the filtered, de-duplicated remainder, got %+v", rest)
+	}
+	if result.result.Format != inputFormatText || result.result.MatchedLines != 3 || result.urls != 2 || len(result.result.Entries) != 0 {
+		t.Fatalf("unexpected stream result %+v (%d urls)", result.result, result.urls)
+	}
+}
+
+func TestStreamInputReadsDocumentFormats(t *testing.T) {
+	content := `{"info": {"args": "-u http://example.com"}, "results": [
+		{"url": "http://example.com/index.php", "status": 200, "content-length": 571, "redirect": ""},
+		{"url": "http://example.com/admin", "status": 301, "content-length": 169, "redirect": "http://example.com/admin/"}
+	]}`
+	entries := make(chan inputEntry, 10)
+	result, urls, err := streamInput(context.Background(), strings.NewReader(content), inputOptions{Filter: defaultStatusFilter()}, entries)
+	if err != nil {
+		t.Fatalf("streamInput returned error: %v", err)
+	}
+
+	got := make([]inputEntry, 0)
+	for entry := range entries {
+		got = append(got, entry)
+	}
+	if result.Format != inputFormatDirsearchJSON || urls != 2 || len(got) != 2 || got[1].Redirect != "http://example.com/admin/" {
+		t.Fatalf("unexpected stream of %s input: %+v", result.Format, got)
+	}
+}
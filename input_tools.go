@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	inputFormatFfufJSON        = "ffuf-json"
+	inputFormatFeroxbusterJSON = "feroxbuster-json"
+	inputFormatFeroxbusterText = "feroxbuster-text"
+	inputFormatGobusterText    = "gobuster-text"
+	inputFormatHttpxJSON       = "httpx-json"
+	inputFormatHttpxText       = "httpx-text"
+)
+
+var (
+	// 301      GET        7l       12w      178c http://host/admin => http://host/admin/
+	feroxbusterLineRegex = regexp.MustCompile(`^(\d{3})\s+[A-Z]+\s+\d+l\s+\d+w\s+(\d+)c\s+(\S+)(?:\s+=>\s+(\S+))?`)
+	// /admin                (Status: 301) [Size: 169] [--> http://host/admin/]
+	gobusterLineRegex = regexp.MustCompile(`^(\S+)\s+\(Status:\s*(\d{3})\)(?:\s+\[Size:\s*(\d+)\])?(?:\s+\[-->\s*([^\]]+)\])?`)
+	// https://host/login [200] [1234] [Login] [https://host/next]
+	httpxLineRegex = regexp.MustCompile(`^(https?://\S+)((?:\s+\[[^\]]*\])+)`)
+	bracketRegex   = regexp.MustCompile(`\[([^\]]*)\]`)
+	statusesRegex  = regexp.MustCompile(`^\d{3}(?:,\d{3})*$`)
+)
+
+// ffufJSONParser reads `ffuf -of json` output.
+type ffufJSONParser struct{}
+
+func (ffufJSONParser) Format() string { return inputFormatFfufJSON }
+
+func (ffufJSONParser) Sniff(head []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(head), []byte("{")) && bytes.Contains(head, []byte(`"commandline"`))
+}
+
+func (ffufJSONParser) Parse(data []byte, collector *inputCollector) error {
+	var document struct {
+		Results []struct {
+			URL              string `json:"url"`
+			Status           int    `json:"status"`
+			Length           int64  `json:"length"`
+			RedirectLocation string `json:"redirectlocation"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(data, &document); err != nil {
+		return err
+	}
+
+	for _, result := range document.Results {
+		collector.add(inputEntry{
+			URL:      result.URL,
+			Status:   result.Status,
+			Size:     result.Length,
+			Redirect: result.RedirectLocation,
+		})
+	}
+	return nil
+}
+
+// feroxbusterJSONParser reads `feroxbuster --json` output: one object per line,
+// of which only "type": "response" lines are hits.
+type feroxbusterJSONParser struct{}
+
+func (feroxbusterJSONParser) Format() string { return inputFormatFeroxbusterJSON }
+
+func (feroxbusterJSONParser) Sniff(head []byte) bool {
+	object, ok := firstJSONLine(head)
+	if !ok {
+		return false
+	}
+	_, hasType := object["type"]
+	return hasType
+}
+
+func (p feroxbusterJSONParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (feroxbusterJSONParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	var record struct {
+		Type          string            `json:"type"`
+		URL           string            `json:"url"`
+		Status        int               `json:"status"`
+		ContentLength int64             `json:"content_length"`
+		Headers       map[string]string `json:"headers"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &record); err != nil || record.Type != "response" {
+		return inputEntry{}, false
+	}
+
+	entry := inputEntry{URL: record.URL, Status: record.Status, Size: record.ContentLength}
+	for name, value := range record.Headers {
+		if strings.EqualFold(name, "location") {
+			entry.Redirect = value
+		}
+	}
+	return entry, true
+}
+
+// feroxbusterTextParser reads feroxbuster's default text output.
+type feroxbusterTextParser struct{}
+
+func (feroxbusterTextParser) Format() string { return inputFormatFeroxbusterText }
+
+func (feroxbusterTextParser) Sniff(head []byte) bool {
+	return anyLineMatches(head, feroxbusterLineRegex)
+}
+
+func (p feroxbusterTextParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (feroxbusterTextParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	match := feroxbusterLineRegex.FindStringSubmatch(strings.TrimSpace(stripANSI(line)))
+	if match == nil {
+		return inputEntry{}, false
+	}
+
+	entry := urlEntry(match[3])
+	entry.Status, _ = strconv.Atoi(match[1])
+	entry.Size = parseSize(match[2])
+	entry.Redirect = match[4]
+	return entry, true
+}
+
+// gobusterTextParser reads `gobuster dir` output. Without --expanded gobuster
+// prints bare paths, which are resolved against inputOptions.BaseURL.
+type gobusterTextParser struct{}
+
+func (gobusterTextParser) Format() string { return inputFormatGobusterText }
+
+func (gobusterTextParser) Sniff(head []byte) bool {
+	return anyLineMatches(head, gobusterLineRegex)
+}
+
+func (p gobusterTextParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (gobusterTextParser) ParseLine(line string, options inputOptions) (inputEntry, bool) {
+	match := gobusterLineRegex.FindStringSubmatch(strings.TrimSpace(stripANSI(line)))
+	if match == nil {
+		return inputEntry{}, false
+	}
+
+	target := match[1]
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		target = joinTargetPath(options.BaseURL, target)
+	}
+
+	entry := urlEntry(target)
+	entry.Status, _ = strconv.Atoi(match[2])
+	entry.Size = parseSize(match[3])
+	entry.Redirect = strings.TrimSpace(match[4])
+	return entry, true
+}
+
+// httpxJSONParser reads `httpx -json` output.
+type httpxJSONParser struct{}
+
+func (httpxJSONParser) Format() string { return inputFormatHttpxJSON }
+
+func (httpxJSONParser) Sniff(head []byte) bool {
+	object, ok := firstJSONLine(head)
+	if !ok {
+		return false
+	}
+	_, hasStatus := object["status_code"]
+	_, hasLegacyStatus := object["status-code"]
+	return hasStatus || hasLegacyStatus
+}
+
+func (p httpxJSONParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (httpxJSONParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	var record struct {
+		URL                 string `json:"url"`
+		StatusCode          int    `json:"status_code"`
+		LegacyStatusCode    int    `json:"status-code"`
+		ContentLength       *int64 `json:"content_length"`
+		LegacyContentLength *int64 `json:"content-length"`
+		Location            string `json:"location"`
+	}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &record); err != nil || record.URL == "" {
+		return inputEntry{}, false
+	}
+
+	entry := urlEntry(record.URL)
+	entry.Status = record.StatusCode
+	if entry.Status == 0 {
+		entry.Status = record.LegacyStatusCode
+	}
+	if record.ContentLength != nil {
+		entry.Size = *record.ContentLength
+	} else if record.LegacyContentLength != nil {
+		entry.Size = *record.LegacyContentLength
+	}
+	entry.Redirect = record.Location
+	return entry, true
+}
+
+// httpxTextParser reads httpx's default text output with bracketed probes,
+// e.g. `-sc -cl -location`. The first bracket holding status codes is the
+// status (the first hop with -fr), the first numeric bracket is the length.
+type httpxTextParser struct{}
+
+func (httpxTextParser) Format() string { return inputFormatHttpxText }
+
+func (httpxTextParser) Sniff(head []byte) bool {
+	return anyLineMatches(head, httpxLineRegex)
+}
+
+func (p httpxTextParser) Parse(data []byte, collector *inputCollector) error {
+	return parseLines(data, p, collector)
+}
+
+func (httpxTextParser) ParseLine(line string, _ inputOptions) (inputEntry, bool) {
+	match := httpxLineRegex.FindStringSubmatch(strings.TrimSpace(stripANSI(line)))
+	if match == nil {
+		return inputEntry{}, false
+	}
+
+	entry := urlEntry(match[1])
+	for _, bracket := range bracketRegex.FindAllStringSubmatch(match[2], -1) {
+		value := strings.TrimSpace(bracket[1])
+		switch {
+		case entry.Status == 0 && statusesRegex.MatchString(value):
+			entry.Status, _ = strconv.Atoi(value[:3])
+		case entry.Size < 0 && isDigits(value):
+			entry.Size = parseSize(value)
+		case entry.Redirect == "" && (strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://")):
+			entry.Redirect = value
+		}
+	}
+	return entry, true
+}
+
+func isDigits(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
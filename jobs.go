@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobPaused    = "paused"
+	jobFinished  = "finished"
+	jobFailed    = "failed"
+	jobCancelled = "cancelled"
+
+	// maxStoredJobs bounds the store; the oldest completed jobs are dropped
+	// first.
+	maxStoredJobs = 200
+)
+
+var errJobDone = errors.New("job already finished")
+
+// ScanJob is a snapshot of a scan submitted to a jobStore. Times are RFC 3339
+// and empty until the job reaches that point. CancelledBeforeStart marks a
+// job cancelled while it was still waiting, which has no progress, rows or
+// report.
+type ScanJob struct {
+	ID                   string       `json:"id"`
+	Status               string       `json:"status"`
+	CancelledBeforeStart bool         `json:"cancelledBeforeStart"`
+	InputFile            string       `json:"inputFile"`
+	Mode                 string       `json:"mode"`
+	Progress             ScanProgress `json:"progress"`
+	Error                string       `json:"error"`
+	ReportPath           string       `json:"reportPath"`
+	CreatedAt            string       `json:"createdAt"`
+	StartedAt            string       `json:"startedAt"`
+	FinishedAt           string       `json:"finishedAt"`
+}
+
+// scanJob runs one scanPlan in the background and keeps its rows and final
+// response after the caller that submitted it has gone.
+type scanJob struct {
+	id      string
+	plan    *scanPlan
+	gate    *scanGate
+	done    chan struct{}
+	created time.Time
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	status   string
+	progress ScanProgress
+	rows     []ScanRow
+	response *ScanResponse
+	err      error
+	started  time.Time
+	finished time.Time
+}
+
+// jobStore holds the jobs of a GUI or server session. Jobs wait in
+// submission order until fewer than maxRunning are running, and the requests
+// of all running jobs share one budget. Jobs are cancelled with the store's
+// context.
+type jobStore struct {
+	ctx    context.Context
+	wg     sync.WaitGroup
+	budget *scanBudget
+
+	// onChange, when set, receives a snapshot whenever a job changes status
+	// or makes progress.
+	onChange func(ScanJob)
+
+	mu         sync.Mutex
+	maxRunning int
+	jobs       []*scanJob
+}
+
+// newJobStore returns a store without limits: every job starts when it is
+// submitted.
+func newJobStore(ctx context.Context) *jobStore {
+	return &jobStore{ctx: ctx, budget: newScanBudget(0)}
+}
+
+// setLimits caps the running jobs and the requests in flight across them;
+// zero removes a cap. Lowering the limits does not stop running requests.
+func (s *jobStore) setLimits(maxRunning, maxRequests int) {
+	s.budget.setLimit(maxRequests)
+
+	s.mu.Lock()
+	s.maxRunning = maxRunning
+	started := s.startQueued()
+	s.mu.Unlock()
+	s.notify(started...)
+}
+
+// submit queues plan and returns its job, which is already running unless
+// the store is at its limit.
+func (s *jobStore) submit(plan *scanPlan) *scanJob {
+	job := &scanJob{
+		id:      randomToken(),
+		plan:    plan,
+		gate:    newScanGate(s.budget),
+		done:    make(chan struct{}),
+		created: time.Now(),
+		status:  jobQueued,
+	}
+	plan.request.gate = job.gate
+
+	s.mu.Lock()
+	s.jobs = append(s.jobs, job)
+	s.prune()
+	s.startQueued()
+	s.mu.Unlock()
+	s.notify(job)
+	return job
+}
+
+// startQueued starts queued jobs, oldest first, while the store is below
+// maxRunning. Paused jobs do not count as running. The caller holds mu.
+func (s *jobStore) startQueued() []*scanJob {
+	if s.ctx.Err() != nil {
+		return nil
+	}
+
+	running := 0
+	for _, job := range s.jobs {
+		if job.state() == jobRunning {
+			running++
+		}
+	}
+
+	var started []*scanJob
+	for _, job := range s.jobs {
+		if s.maxRunning > 0 && running >= s.maxRunning {
+			break
+		}
+		if !s.start(job) {
+			continue
+		}
+		started = append(started, job)
+		running++
+	}
+	return started
+}
+
+// start runs job if it is still queued. The status is checked under the
+// job's lock, so a job cancelled meanwhile is never started.
+func (s *jobStore) start(job *scanJob) bool {
+	job.mu.Lock()
+	if job.status != jobQueued {
+		job.mu.Unlock()
+		return false
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	job.status = jobRunning
+	job.started = time.Now()
+	job.cancel = cancel
+	job.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		job.run(ctx, func() { s.notify(job) })
+
+		s.mu.Lock()
+		started := s.startQueued()
+		s.mu.Unlock()
+		s.notify(append([]*scanJob{job}, started...)...)
+	}()
+	return true
+}
+
+// pause holds a job's workers once their current requests finish, or keeps a
+// queued job from starting. The slot it held goes to the next queued job.
+func (s *jobStore) pause(job *scanJob) error {
+	s.mu.Lock()
+	job.mu.Lock()
+	if !job.finished.IsZero() {
+		job.mu.Unlock()
+		s.mu.Unlock()
+		return errJobDone
+	}
+	job.status = jobPaused
+	job.gate.pause()
+	job.mu.Unlock()
+	started := s.startQueued()
+	s.mu.Unlock()
+
+	s.notify(append([]*scanJob{job}, started...)...)
+	return nil
+}
+
+// resume continues a paused job. A job paused before it started goes back to
+// the queue; one paused mid-scan continues at once.
+func (s *jobStore) resume(job *scanJob) error {
+	s.mu.Lock()
+	job.mu.Lock()
+	if !job.finished.IsZero() {
+		job.mu.Unlock()
+		s.mu.Unlock()
+		return errJobDone
+	}
+	if job.status == jobPaused {
+		if job.cancel == nil {
+			job.status = jobQueued
+		} else {
+			job.status = jobRunning
+		}
+		job.gate.resume()
+	}
+	job.mu.Unlock()
+	started := s.startQueued()
+	s.mu.Unlock()
+
+	s.notify(append([]*scanJob{job}, started...)...)
+	return nil
+}
+
+// cancel stops a job. A running job still reports the rows that finished; a
+// job that never started is marked as cancelled before start and gets no
+// report.
+func (s *jobStore) cancel(job *scanJob) error {
+	s.mu.Lock()
+	job.mu.Lock()
+	if !job.finished.IsZero() {
+		job.mu.Unlock()
+		s.mu.Unlock()
+		return errJobDone
+	}
+	if job.cancel != nil {
+		job.cancel()
+		job.mu.Unlock()
+		s.mu.Unlock()
+		return nil
+	}
+	job.status = jobCancelled
+	job.finish()
+	job.mu.Unlock()
+	s.mu.Unlock()
+
+	s.notify(job)
+	return nil
+}
+
+func (s *jobStore) notify(jobs ...*scanJob) {
+	if s.onChange == nil {
+		return
+	}
+	for _, job := range jobs {
+		s.onChange(job.snapshot())
+	}
+}
+
+// prune drops the oldest completed jobs beyond maxStoredJobs. Jobs that have
+// not finished are always kept.
+func (s *jobStore) prune() {
+	excess := len(s.jobs) - maxStoredJobs
+	kept := s.jobs[:0]
+	for _, job := range s.jobs {
+		if excess > 0 && job.isDone() {
+			excess--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	s.jobs = kept
+}
+
+func (s *jobStore) get(id string) (*scanJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.id == id {
+			return job, true
+		}
+	}
+	return nil, false
+}
+
+// list returns every job, oldest first.
+func (s *jobStore) list() []ScanJob {
+	s.mu.Lock()
+	jobs := append([]*scanJob(nil), s.jobs...)
+	s.mu.Unlock()
+
+	snapshots := make([]ScanJob, 0, len(jobs))
+	for _, job := range jobs {
+		snapshots = append(snapshots, job.snapshot())
+	}
+	return snapshots
+}
+
+// wait blocks until every job has finished writing its report.
+func (s *jobStore) wait() {
+	s.wg.Wait()
+}
+
+// run scans the plan and writes its report. onProgress is called after every
+// finished URL.
+func (j *scanJob) run(ctx context.Context, onProgress func()) {
+	defer j.cancel()
+
+	response, err := j.plan.run(ctx, j.addRow, func(progress ScanProgress) {
+		j.setProgress(progress)
+		onProgress()
+	})
+	if err == nil {
+		err = finishScan(j.plan.request, &response)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	switch {
+	case err != nil:
+		j.status, j.err = jobFailed, err
+	case response.Cancelled:
+		j.status, j.response = jobCancelled, &response
+	default:
+		j.status, j.response = jobFinished, &response
+	}
+	if j.response != nil {
+		// The final rows replace the streamed ones.
+		j.rows = nil
+	}
+	j.finish()
+}
+
+// finish marks the job as done. A job finishes exactly once: either run
+// returns or the job is cancelled while it never started. The caller holds
+// mu.
+func (j *scanJob) finish() {
+	j.finished = time.Now()
+	close(j.done)
+}
+
+func (j *scanJob) addRow(event ScanRowEvent) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.rows = append(j.rows, event.Row)
+}
+
+func (j *scanJob) setProgress(progress ScanProgress) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.progress = progress
+}
+
+func (j *scanJob) state() string {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status
+}
+
+func (j *scanJob) isDone() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.finished.IsZero()
+}
+
+func (j *scanJob) snapshot() ScanJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	job := ScanJob{
+		ID:                   j.id,
+		Status:               j.status,
+		CancelledBeforeStart: j.status == jobCancelled && j.started.IsZero(),
+		InputFile:            j.plan.request.InputFilePath,
+		Mode:                 j.plan.request.Mode,
+		Progress:             j.progress,
+		CreatedAt:            formatJobTime(j.created),
+		StartedAt:            formatJobTime(j.started),
+		FinishedAt:           formatJobTime(j.finished),
+	}
+	if j.err != nil {
+		job.Error = j.err.Error()
+	}
+	if j.response != nil {
+		job.ReportPath = j.response.ReportPath
+	}
+	return job
+}
+
+// rowsPage returns up to limit rows starting at offset, and the number of rows
+// so far. Until the job completes these are the rows streamed by the workers;
+// afterwards they are the final, post-processed rows.
+func (j *scanJob) rowsPage(offset, limit int) ([]ScanRow, int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rows := j.rows
+	if j.response != nil {
+		rows = j.response.Rows
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	end := len(rows)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return append([]ScanRow(nil), rows[offset:end]...), len(rows)
+}
+
+// result returns the final response once the job has finished or was
+// cancelled.
+func (j *scanJob) result() (ScanResponse, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.response == nil {
+		return ScanResponse{}, false
+	}
+	return *j.response, true
+}
+
+func formatJobTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(time.RFC3339)
+}
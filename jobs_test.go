@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newBlockingServer answers /slow only once release is closed and counts
+// every request it receives.
+func newBlockingServer(t *testing.T) (*httptest.Server, chan struct{}, *atomic.Int64) {
+	t.Helper()
+	release := make(chan struct{})
+	hits := &atomic.Int64{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if r.URL.Path == "/slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_, _ = w.Write([]byte("<title>job</title>"))
+	}))
+	t.Cleanup(server.Close)
+	return server, release, hits
+}
+
+func newTestPlan(t *testing.T, lines ...string) *scanPlan {
+	t.Helper()
+	plan, err := prepareScan(normalizeScanRequest(ScanRequest{
+		InputFilePath:  writeCLIInput(t, lines...),
+		Concurrency:    1,
+		DisableFavicon: true,
+	}))
+	if err != nil {
+		t.Fatalf("prepare scan: %v", err)
+	}
+	return plan
+}
+
+func waitForStoreJob(t *testing.T, job *scanJob) ScanJob {
+	t.Helper()
+	select {
+	case <-job.done:
+	case <-time.After(10 * time.Second):
+		t.Fatalf("job %s did not finish: %+v", job.id, job.snapshot())
+	}
+	return job.snapshot()
+}
+
+func TestJobStoreQueuesJobsBeyondTheLimit(t *testing.T) {
+	server, release, _ := newBlockingServer(t)
+	jobs := newJobStore(context.Background())
+	jobs.setLimits(1, 0)
+
+	first := jobs.submit(newTestPlan(t, "200 "+server.URL+"/slow"))
+	second := jobs.submit(newTestPlan(t, "200 "+server.URL+"/fast"))
+	third := jobs.submit(newTestPlan(t, "200 "+server.URL+"/fast"))
+	if first.state() != jobRunning || second.state() != jobQueued || third.state() != jobQueued {
+		t.Fatalf("expected one running job, got %s %s %s", first.state(), second.state(), third.state())
+	}
+
+	if err := jobs.cancel(third); err != nil {
+		t.Fatalf("cancel queued job: %v", err)
+	}
+	if job := third.snapshot(); job.Status != jobCancelled || !job.CancelledBeforeStart || job.StartedAt != "" || job.Progress.Total != 0 {
+		t.Fatalf("expected the queued job to be dropped, got %+v", job)
+	}
+	if _, ok := third.result(); ok {
+		t.Fatal("expected no result for a job that never started")
+	}
+
+	close(release)
+	if job := waitForStoreJob(t, first); job.Status != jobFinished {
+		t.Fatalf("unexpected first job %+v", job)
+	}
+	if job := waitForStoreJob(t, second); job.Status != jobFinished || job.Progress.Done != 1 {
+		t.Fatalf("expected the queued job to run next, got %+v", job)
+	}
+	if err := jobs.cancel(second); err != errJobDone {
+		t.Fatalf("expected errJobDone, got %v", err)
+	}
+	jobs.wait()
+}
+
+func TestJobStoreCancelsQueuedJobsWhileTheyStart(t *testing.T) {
+	server, release, _ := newBlockingServer(t)
+	close(release)
+
+	for i := 0; i < 20; i++ {
+		jobs := newJobStore(context.Background())
+		jobs.setLimits(1, 0)
+		first := jobs.submit(newTestPlan(t, "200 "+server.URL+"/fast"))
+		queued := make([]*scanJob, 20)
+		for j := range queued {
+			queued[j] = jobs.submit(newTestPlan(t, "200 "+server.URL+"/fast"))
+		}
+
+		// Lifting the limit starts the queued jobs while they are cancelled.
+		var wg sync.WaitGroup
+		for _, job := range queued {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := jobs.cancel(job); err != nil && err != errJobDone {
+					t.Errorf("cancel: %v", err)
+				}
+			}()
+		}
+		jobs.setLimits(0, 0)
+		wg.Wait()
+
+		waitForStoreJob(t, first)
+		for _, job := range queued {
+			if got := waitForStoreJob(t, job); got.Status != jobCancelled && got.Status != jobFinished {
+				t.Fatalf("expected the job to be cancelled or finished, got %+v", got)
+			}
+		}
+		jobs.wait()
+	}
+}
+
+func TestJobStorePausesAndResumesJobs(t *testing.T) {
+	server, release, hits := newBlockingServer(t)
+	jobs := newJobStore(context.Background())
+	jobs.setLimits(1, 0)
+
+	var changes atomic.Int64
+	jobs.onChange = func(ScanJob) { changes.Add(1) }
+
+	running := jobs.submit(newTestPlan(t, "200 "+server.URL+"/slow", "200 "+server.URL+"/a", "200 "+server.URL+"/b"))
+	for hits.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// Pausing the running job frees its slot for the next one.
+	if err := jobs.pause(running); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	next := jobs.submit(newTestPlan(t, "200 "+server.URL+"/c"))
+	if job := waitForStoreJob(t, next); job.Status != jobFinished {
+		t.Fatalf("expected the next job to run while the first is paused, got %+v", job)
+	}
+
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+	if got := hits.Load(); got != 2 || running.state() != jobPaused {
+		t.Fatalf("expected no new requests while paused, got %d requests, status %s", got, running.state())
+	}
+
+	if err := jobs.resume(running); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if job := waitForStoreJob(t, running); job.Status != jobFinished || job.Progress.Done != 3 {
+		t.Fatalf("expected the resumed job to finish, got %+v", job)
+	}
+	if err := jobs.pause(running); err != errJobDone {
+		t.Fatalf("expected errJobDone, got %v", err)
+	}
+	if changes.Load() == 0 {
+		t.Fatal("expected job updates")
+	}
+	jobs.wait()
+}
+
+func TestJobStoreKeepsTheRateLimitAcrossAPause(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		_, _ = w.Write([]byte("<title>job</title>"))
+	}))
+	defer server.Close()
+
+	plan, err := prepareScan(normalizeScanRequest(ScanRequest{
+		InputFilePath:     writeCLIInput(t, "200 "+server.URL+"/a", "200 "+server.URL+"/b", "200 "+server.URL+"/c"),
+		Concurrency:       1,
+		RequestsPerSecond: 5,
+		DisableFavicon:    true,
+	}))
+	if err != nil {
+		t.Fatalf("prepare scan: %v", err)
+	}
+	jobs := newJobStore(context.Background())
+	job := jobs.submit(plan)
+	for {
+		mu.Lock()
+		started := len(times) > 0
+		mu.Unlock()
+		if started {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := jobs.pause(job); err != nil {
+		t.Fatalf("pause: %v", err)
+	}
+	// Long enough for the scheduler to have handed out the remaining jobs
+	// if it ignored the pause.
+	time.Sleep(600 * time.Millisecond)
+	if err := jobs.resume(job); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if got := waitForStoreJob(t, job); got.Status != jobFinished {
+		t.Fatalf("expected the job to finish, got %+v", got)
+	}
+	jobs.wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 150*time.Millisecond {
+			t.Fatalf("expected requests to stay 5 per second after resuming, request %d came %v after the previous one", i, gap)
+		}
+	}
+}
+
+func TestJobStoreResumesJobsPausedBeforeStarting(t *testing.T) {
+	server, release, _ := newBlockingServer(t)
+	jobs := newJobStore(context.Background())
+	jobs.setLimits(1, 0)
+
+	first := jobs.submit(newTestPlan(t, "200 "+server.URL+"/slow"))
+	second := jobs.submit(newTestPlan(t, "200 "+server.URL+"/fast"))
+	if err := jobs.pause(second); err != nil {
+		t.Fatalf("pause queued job: %v", err)
+	}
+
+	close(release)
+	waitForStoreJob(t, first)
+	if second.state() != jobPaused {
+		t.Fatalf("expected the paused job to stay out of the queue, got %s", second.state())
+	}
+
+	if err := jobs.resume(second); err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if job := waitForStoreJob(t, second); job.Status != jobFinished {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	jobs.wait()
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const replayConcurrency = 4
+
+// parseProxyURL accepts http, https, socks5 and socks5h proxies, with optional
+// user:password credentials. A bare host:port is treated as an http proxy.
+func parseProxyURL(raw string) (*url.URL, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+
+	proxyURL, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", raw, err)
+	}
+
+	switch strings.ToLower(proxyURL.Scheme) {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: missing host", raw)
+	}
+
+	return proxyURL, nil
+}
+
+// parseProxyList parses ProxyURL followed by every entry of ProxyList, which
+// holds one proxy per line or comma separated. Lines starting with "#" are
+// skipped.
+func parseProxyList(request ScanRequest) ([]*url.URL, error) {
+	fields := []string{request.ProxyURL}
+	fields = append(fields, strings.FieldsFunc(request.ProxyList, func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	})...)
+
+	proxies := make([]*url.URL, 0, len(fields))
+	for _, field := range fields {
+		field = strings.TrimSpace(field)
+		if field == "" || strings.HasPrefix(field, "#") {
+			continue
+		}
+
+		proxyURL, err := parseProxyURL(field)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, proxyURL)
+	}
+	return proxies, nil
+}
+
+// proxyRotator hands out its proxies round-robin, one per request.
+type proxyRotator struct {
+	proxies []*url.URL
+	next    atomic.Uint64
+}
+
+func (r *proxyRotator) Proxy(*http.Request) (*url.URL, error) {
+	index := r.next.Add(1) - 1
+	return r.proxies[index%uint64(len(r.proxies))], nil
+}
+
+// newProxyFunc returns the Transport.Proxy for the request. Without any
+// configured proxy the environment (HTTP_PROXY etc.) is honoured as before.
+func newProxyFunc(request ScanRequest) (func(*http.Request) (*url.URL, error), error) {
+	proxies, err := parseProxyList(request)
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(proxies) {
+	case 0:
+		return http.ProxyFromEnvironment, nil
+	case 1:
+		return http.ProxyURL(proxies[0]), nil
+	default:
+		return (&proxyRotator{proxies: proxies}).Proxy, nil
+	}
+}
+
+// replayHits sends every successful row once more through the replay proxy,
+// typically Burp Suite, so only real findings land in its site map. Soft-404
+// rows are left out. The replay uses the scan's TLS settings, so a proxy that
+// re-signs TLS traffic needs its CA in the CA bundle or the insecure mode, and
+// it goes through a host scheduler of its own with the scan's rate and
+// per-host limits. Each row is replayed with the browser profile and auth
+// headers it was scanned with. It returns the number of rows the proxy
+// accepted.
+func replayHits(ctx context.Context, rows []ScanRow, request ScanRequest) (int, error) {
+	proxyURL, err := parseProxyURL(request.ReplayProxyURL)
+	if err != nil {
+		return 0, err
+	}
+
+	auth, err := compileAuthRules(request.AuthRules)
+	if err != nil {
+		return 0, err
+	}
+
+	tlsConfig, err := newTLSConfig(request)
+	if err != nil {
+		return 0, err
+	}
+
+	timeoutSeconds := request.TimeoutSeconds
+	if timeoutSeconds <= 0 {
+		timeoutSeconds = defaultTimeoutSecond
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(proxyURL)
+	transport.TLSClientConfig = tlsConfig
+	client := &http.Client{
+		Timeout:   time.Duration(timeoutSeconds) * time.Second,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	defer client.CloseIdleConnections()
+
+	scheduler := newHostScheduler(request)
+	scheduler.workers = replayConcurrency
+
+	incoming := make(chan indexedEntry)
+	jobs := make(chan indexedEntry)
+	done := make(chan string, replayConcurrency)
+	var (
+		wg       sync.WaitGroup
+		replayed atomic.Int64
+	)
+	for i := 0; i < replayConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if replayURL(ctx, client, rows[job.Index], auth) {
+					replayed.Add(1)
+				}
+				request.gate.release()
+				done <- job.Host
+			}
+		}()
+	}
+
+	go func() {
+		scheduler.run(ctx, incoming, jobs, done)
+		// Workers still report the jobs that were in flight.
+		for range done {
+		}
+	}()
+
+feed:
+	for index, row := range rows {
+		if row.Error != "" || row.StatusCode == 0 || row.WildcardMatch {
+			continue
+		}
+		select {
+		case incoming <- indexedEntry{Index: index, Entry: urlEntry(row.URL), Host: hostKey(row.URL)}:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(incoming)
+	wg.Wait()
+	close(done)
+
+	return int(replayed.Load()), nil
+}
+
+func replayURL(ctx context.Context, client *http.Client, row ScanRow, auth authRules) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, row.URL, nil)
+	if err != nil {
+		return false
+	}
+	for _, header := range browserProfileByName(row.BrowserProfile).headers {
+		req.Header.Set(header.name, header.value)
+	}
+	auth.apply(req)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
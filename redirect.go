@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const maxRedirects = 10
+
+// loginPathRegex recognises the usual login, SSO and CAS entry points.
+var loginPathRegex = regexp.MustCompile(`(?i)(log[-_]?in|sign[-_]?in|/sso\b|/cas/|/oauth|/passport|/auth(?:[/?.]|$)|wp-login\.php|/session/new|/account/logon)`)
+
+// RedirectHop is one 3xx response of a redirect chain.
+type RedirectHop struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"statusCode"`
+	Location   string `json:"location"`
+}
+
+type redirectChainKey struct{}
+
+// withRedirectChain returns a context whose requests record every redirect
+// they meet into the returned slice.
+func withRedirectChain(ctx context.Context) (context.Context, *[]RedirectHop) {
+	chain := &[]RedirectHop{}
+	return context.WithValue(ctx, redirectChainKey{}, chain), chain
+}
+
+// checkRedirect records the hop that led to req and then either stops, when
+// redirects are not followed, or follows up to maxRedirects hops with the
+// auth rules of the new host.
+func checkRedirect(follow bool, auth authRules) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if chain, ok := req.Context().Value(redirectChainKey{}).(*[]RedirectHop); ok && req.Response != nil {
+			*chain = append(*chain, RedirectHop{
+				URL:        req.Response.Request.URL.String(),
+				StatusCode: req.Response.StatusCode,
+				Location:   req.URL.String(),
+			})
+		}
+
+		if !follow {
+			return http.ErrUseLastResponse
+		}
+		if len(via) >= maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		auth.redirect(req)
+		return nil
+	}
+}
+
+// scanScope is the set of hostnames present in the scan input; redirects
+// leaving it are flagged as off-scope. Streamed scans add hosts as their
+// entries arrive, so it is safe for concurrent use.
+type scanScope struct {
+	mu    sync.RWMutex
+	hosts map[string]struct{}
+}
+
+func newScanScope(entries []inputEntry) *scanScope {
+	scope := &scanScope{hosts: make(map[string]struct{})}
+	for _, entry := range entries {
+		scope.add(entry.URL)
+	}
+	return scope
+}
+
+func (s *scanScope) add(rawURL string) {
+	if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" {
+		s.mu.Lock()
+		s.hosts[strings.ToLower(parsed.Hostname())] = struct{}{}
+		s.mu.Unlock()
+	}
+}
+
+// excludes reports whether host is outside a non-empty scope.
+func (s *scanScope) excludes(host string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.hosts[host]
+	return len(s.hosts) > 0 && !ok
+}
+
+// flagRedirects marks rows whose chain leaves the original host, leaves the
+// scan's scope or ends on a login page.
+func flagRedirects(row *ScanRow, scope *scanScope) {
+	if len(row.RedirectChain) == 0 {
+		return
+	}
+
+	origin := redirectHostname(row.URL)
+	for _, hop := range row.RedirectChain {
+		host := redirectHostname(hop.Location)
+		if host == "" {
+			continue
+		}
+		if host != origin {
+			row.CrossHostRedirect = true
+		}
+		if scope.excludes(host) {
+			row.OffScopeRedirect = true
+		}
+		if parsed, err := url.Parse(hop.Location); err == nil && loginPathRegex.MatchString(parsed.RequestURI()) {
+			row.LoginRedirect = true
+		}
+	}
+}
+
+func redirectHostname(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunScanWorkersRecordsRedirectChain(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<title>Sign in</title>"))
+	}))
+	defer external.Close()
+	// Same server, reached through a hostname that is not in the input.
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/admin":
+			http.Redirect(w, r, "/admin/", http.StatusMovedPermanently)
+		case "/admin/":
+			http.Redirect(w, r, externalURL+"/cas/login?service=admin", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	entries := []inputEntry{urlEntry(server.URL + "/admin"), urlEntry(server.URL + "/plain")}
+	for _, follow := range []bool{true, false} {
+		rows, err := runScanWorkers(context.Background(), entries, ScanRequest{
+			Concurrency:    1,
+			TimeoutSeconds: 5,
+			FollowRedirect: follow,
+		}, nil)
+		if err != nil {
+			t.Fatalf("runScanWorkers returned error: %v", err)
+		}
+
+		admin, plain := rows[0], rows[1]
+		if len(plain.RedirectChain) != 0 || plain.CrossHostRedirect || plain.LoginRedirect {
+			t.Fatalf("expected no redirect for plain URL, got %+v", plain)
+		}
+
+		if !follow {
+			if len(admin.RedirectChain) != 1 || admin.RedirectChain[0].StatusCode != http.StatusMovedPermanently || admin.RedirectChain[0].Location != server.URL+"/admin/" {
+				t.Fatalf("expected the unfollowed 301 as only hop, got %+v", admin.RedirectChain)
+			}
+			if admin.CrossHostRedirect || admin.OffScopeRedirect || admin.LoginRedirect {
+				t.Fatalf("expected a same-host redirect to carry no flags, got %+v", admin)
+			}
+			continue
+		}
+
+		if len(admin.RedirectChain) != 2 {
+			t.Fatalf("expected 2 hops, got %+v", admin.RedirectChain)
+		}
+		second := admin.RedirectChain[1]
+		if second.URL != server.URL+"/admin/" || second.StatusCode != http.StatusFound || !strings.HasPrefix(second.Location, externalURL) {
+			t.Fatalf("unexpected second hop %+v", second)
+		}
+		if !admin.CrossHostRedirect || !admin.OffScopeRedirect || !admin.LoginRedirect {
+			t.Fatalf("expected cross-host, off-scope and login flags, got %+v", admin)
+		}
+		if admin.Title != "Sign in" {
+			t.Fatalf("expected the final page to be inspected, got %q", admin.Title)
+		}
+	}
+}
+
+func TestAppendMarkdownReportRendersRedirectChains(t *testing.T) {
+	reportPath := filepath.Join(t.TempDir(), "scan_report.md")
+	response := ScanResponse{
+		TotalURLs: 1,
+		Rows: []ScanRow{{
+			URL:        "http://example.com/admin",
+			StatusCode: 200,
+			RedirectChain: []RedirectHop{
+				{URL: "http://example.com/admin", StatusCode: 302, Location: "https://sso.example.net/login"},
+			},
+			CrossHostRedirect: true,
+			LoginRedirect:     true,
+		}},
+	}
+
+	if err := appendMarkdownReport(reportPath, "input.txt", response); err != nil {
+		t.Fatalf("appendMarkdownReport returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	content := string(data)
+	for _, want := range []string{
+		"### Redirect Chains",
+		"- `http://example.com/admin` **cross-host** **login**",
+		"  1. 302 `http://example.com/admin` -> `https://sso.example.net/login`",
+	} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected report to contain %q:\n%s", want, content)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -14,49 +16,341 @@ func appendMarkdownReport(reportPath, inputFilePath string, response ScanRespons
 	}
 	defer file.Close()
 
+	if _, err := file.WriteString(buildMarkdownReport(inputFilePath, response)); err != nil {
+		return fmt.Errorf("write report file: %w", err)
+	}
+
+	return nil
+}
+
+// buildMarkdownReport renders one scan as a Markdown section; reports append
+// one section per run.
+func buildMarkdownReport(inputFilePath string, response ScanResponse) string {
 	now := time.Now().Format("2006-01-02 15:04:05")
+	statusLabel := response.StatusFilter
+	if statusLabel == "" {
+		statusLabel = defaultStatusFilter().String()
+	}
+
 	builder := strings.Builder{}
 	builder.WriteString(fmt.Sprintf("## Scan Report - %s\n", now))
-	builder.WriteString(fmt.Sprintf("- Input File: `%s`\n", inputFilePath))
-	builder.WriteString(fmt.Sprintf("- Total Matched Lines (200/301/403): %d\n", response.Total200Lines))
+	if len(response.InputFiles) > 0 {
+		builder.WriteString(fmt.Sprintf("- Input Files: %d\n", len(response.InputFiles)))
+	} else {
+		builder.WriteString(fmt.Sprintf("- Input File: `%s`\n", inputFilePath))
+	}
+	if response.InputFormat != "" {
+		builder.WriteString(fmt.Sprintf("- Input Format: %s\n", response.InputFormat))
+	}
+	if response.Cancelled {
+		builder.WriteString("- Status: Cancelled (partial results)\n")
+	}
+	if response.Mode == scanModeDiscover {
+		builder.WriteString("- Mode: discover\n")
+		builder.WriteString(fmt.Sprintf("- Wordlist: `%s`\n", response.Wordlist))
+		builder.WriteString(fmt.Sprintf("- Total Hits (%s): %d\n", statusLabel, response.TotalMatchedLines))
+	} else {
+		builder.WriteString(fmt.Sprintf("- Total Matched Lines (%s): %d\n", statusLabel, response.TotalMatchedLines))
+	}
 	builder.WriteString(fmt.Sprintf("- Total URLs: %d\n", response.TotalURLs))
 	builder.WriteString(fmt.Sprintf("- Succeeded: %d\n", response.Succeeded))
-	builder.WriteString(fmt.Sprintf("- Failed: %d\n\n", response.Failed))
-	builder.WriteString("| URL | Title | Components | Error |\n")
-	builder.WriteString("| --- | --- | --- | --- |\n")
+	builder.WriteString(fmt.Sprintf("- Failed: %d\n", response.Failed))
+	if response.WildcardMatches > 0 {
+		builder.WriteString(fmt.Sprintf("- Soft-404 / Wildcard Matches: %d\n", response.WildcardMatches))
+	}
+	if response.Replayed > 0 {
+		builder.WriteString(fmt.Sprintf("- Replayed to Proxy: %d\n", response.Replayed))
+	}
+	builder.WriteString("\n")
+	builder.WriteString("| URL | Source | Status | Title | Components | Content-Type | Length | Time | Location | Error |\n")
+	builder.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- | --- | --- |\n")
 
-	if len(response.Rows) == 0 {
-		builder.WriteString("| N/A | N/A | N/A | No URL found from matched lines (200/301/403) |\n\n")
+	if len(response.Rows) == 0 && response.Cancelled {
+		builder.WriteString("| N/A | - | - | N/A | N/A | - | - | - | - | Scan cancelled before any URL finished |\n\n")
+	} else if len(response.Rows) == 0 && response.Mode == scanModeDiscover {
+		builder.WriteString(fmt.Sprintf("| N/A | - | - | N/A | N/A | - | - | - | - | No hit found (%s) |\n\n", escapeMarkdownCell(statusLabel)))
+	} else if len(response.Rows) == 0 {
+		builder.WriteString(fmt.Sprintf("| N/A | - | - | N/A | N/A | - | - | - | - | No URL found from matched lines (%s) |\n\n", escapeMarkdownCell(statusLabel)))
 	} else {
 		for _, row := range response.Rows {
-			components := "N/A"
-			if len(row.Components) > 0 {
-				components = strings.Join(row.Components, ", ")
-			}
-
 			errorText := row.Error
 			if errorText == "" {
 				errorText = "-"
 			}
 
-			builder.WriteString("| ")
-			builder.WriteString(escapeMarkdownCell(row.URL))
-			builder.WriteString(" | ")
-			builder.WriteString(escapeMarkdownCell(row.Title))
-			builder.WriteString(" | ")
-			builder.WriteString(escapeMarkdownCell(components))
-			builder.WriteString(" | ")
-			builder.WriteString(escapeMarkdownCell(errorText))
-			builder.WriteString(" |\n")
+			writeMarkdownRow(&builder,
+				formatReportURL(row),
+				formatSourceMeta(row),
+				formatRowStatus(row),
+				row.Title,
+				formatComponents(row),
+				orDash(row.ContentType),
+				formatContentLength(row),
+				formatResponseTime(row),
+				orDash(row.Location),
+				errorText,
+			)
 		}
 		builder.WriteString("\n")
 	}
+	writeInputFiles(&builder, response.InputFiles, response.SkippedFiles)
+	writeRedirectChains(&builder, response.Rows)
+	writeTLSCertificates(&builder, response.Rows)
+	writeFaviconHashes(&builder, response.Rows)
+	writeSANPivots(&builder, response.SANPivots)
 
-	if _, err := file.WriteString(builder.String()); err != nil {
-		return fmt.Errorf("write report file: %w", err)
+	return builder.String()
+}
+
+// writeInputFiles lists the files of a batch scan with what each contributed,
+// and the reports of earlier scans it left out.
+func writeInputFiles(builder *strings.Builder, files []InputFileSummary, skipped []string) {
+	if len(files) == 0 {
+		return
 	}
 
-	return nil
+	builder.WriteString("### Input Files\n\n")
+	builder.WriteString("| File | Format | Matched Lines | URLs |\n")
+	builder.WriteString("| --- | --- | --- | --- |\n")
+	for _, file := range files {
+		writeMarkdownRow(builder,
+			file.Path,
+			file.Format,
+			strconv.Itoa(file.MatchedLines),
+			strconv.Itoa(file.URLs),
+		)
+	}
+	builder.WriteString("\n")
+	if len(skipped) > 0 {
+		builder.WriteString("Skipped reports of earlier scans:\n\n")
+		for _, path := range skipped {
+			builder.WriteString(fmt.Sprintf("- `%s`\n", path))
+		}
+		builder.WriteString("\n")
+	}
+}
+
+// writeRedirectChains lists every row that was redirected, one hop per line,
+// with its cross-host, off-scope and login flags.
+func writeRedirectChains(builder *strings.Builder, rows []ScanRow) {
+	header := false
+	for _, row := range rows {
+		if len(row.RedirectChain) == 0 {
+			continue
+		}
+		if !header {
+			builder.WriteString("### Redirect Chains\n\n")
+			header = true
+		}
+
+		builder.WriteString(fmt.Sprintf("- `%s`", row.URL))
+		if flags := formatRedirectFlags(row); flags != "" {
+			builder.WriteString(" " + flags)
+		}
+		builder.WriteString("\n")
+		for i, hop := range row.RedirectChain {
+			builder.WriteString(fmt.Sprintf("  %d. %d `%s` -> `%s`\n", i+1, hop.StatusCode, hop.URL, hop.Location))
+		}
+	}
+	if header {
+		builder.WriteString("\n")
+	}
+}
+
+// writeTLSCertificates lists each distinct certificate once per host.
+func writeTLSCertificates(builder *strings.Builder, rows []ScanRow) {
+	seen := make(map[string]struct{})
+	header := false
+	for _, row := range rows {
+		if row.TLS == nil {
+			continue
+		}
+
+		host := hostKey(row.FinalURL)
+		if host == "" {
+			host = hostKey(row.URL)
+		}
+		key := host + "|" + row.TLS.Fingerprint
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		if !header {
+			builder.WriteString("### TLS Certificates\n\n")
+			builder.WriteString("| Host | Subject | Issuer | SANs | Valid | Key | TLS | Flags |\n")
+			builder.WriteString("| --- | --- | --- | --- | --- | --- | --- | --- |\n")
+			header = true
+		}
+
+		protocol := strings.TrimSpace(row.TLS.Version + " " + row.TLS.CipherSuite)
+		writeMarkdownRow(builder,
+			host,
+			row.TLS.Subject,
+			row.TLS.Issuer,
+			orDash(strings.Join(row.TLS.SANs, ", ")),
+			fmt.Sprintf("%s ~ %s", formatCertificateDate(row.TLS.NotBefore), formatCertificateDate(row.TLS.NotAfter)),
+			row.TLS.KeyType,
+			orDash(protocol),
+			orDash(formatTLSFlags(row.TLS)),
+		)
+	}
+	if header {
+		builder.WriteString("\n")
+	}
+}
+
+func formatCertificateDate(value string) string {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.Format("2006-01-02")
+	}
+	return value
+}
+
+// writeFaviconHashes lists each host's favicon with its mmh3 hash, ready to
+// paste into a Shodan (http.favicon.hash:) or FOFA (icon_hash=) query.
+func writeFaviconHashes(builder *strings.Builder, rows []ScanRow) {
+	seen := make(map[string]struct{})
+	header := false
+	for _, row := range rows {
+		if row.FaviconMD5 == "" {
+			continue
+		}
+		if _, ok := seen[row.FaviconURL]; ok {
+			continue
+		}
+		seen[row.FaviconURL] = struct{}{}
+
+		if !header {
+			builder.WriteString("### Favicon Hashes\n\n")
+			builder.WriteString("| Favicon | mmh3 | MD5 |\n")
+			builder.WriteString("| --- | --- | --- |\n")
+			header = true
+		}
+		writeMarkdownRow(builder,
+			row.FaviconURL,
+			strconv.Itoa(int(row.FaviconHash)),
+			row.FaviconMD5,
+		)
+	}
+	if header {
+		builder.WriteString("\n")
+	}
+}
+
+// writeSANPivots lists certificate hostnames that were not scanned.
+func writeSANPivots(builder *strings.Builder, pivots []string) {
+	if len(pivots) == 0 {
+		return
+	}
+
+	builder.WriteString("### SAN Pivot Candidates\n\n")
+	for _, name := range pivots {
+		builder.WriteString(fmt.Sprintf("- `%s`\n", name))
+	}
+	builder.WriteString("\n")
+}
+
+func formatRedirectFlags(row ScanRow) string {
+	flags := make([]string, 0, 3)
+	if row.CrossHostRedirect {
+		flags = append(flags, "**cross-host**")
+	}
+	if row.OffScopeRedirect {
+		flags = append(flags, "**off-scope**")
+	}
+	if row.LoginRedirect {
+		flags = append(flags, "**login**")
+	}
+	return strings.Join(flags, " ")
+}
+
+func writeMarkdownRow(builder *strings.Builder, cells ...string) {
+	builder.WriteString("|")
+	for _, cell := range cells {
+		builder.WriteString(" ")
+		builder.WriteString(escapeMarkdownCell(cell))
+		builder.WriteString(" |")
+	}
+	builder.WriteString("\n")
+}
+
+func formatReportURL(row ScanRow) string {
+	if row.FinalURL == "" || row.FinalURL == row.URL {
+		return row.URL
+	}
+	return fmt.Sprintf("%s\n-> %s", row.URL, row.FinalURL)
+}
+
+// formatSourceMeta renders what the input report said about the URL, e.g.
+// "301 169B -> https://example.com/admin/".
+func formatSourceMeta(row ScanRow) string {
+	var parts []string
+	if row.SourceStatus != 0 {
+		parts = append(parts, strconv.Itoa(row.SourceStatus))
+		if row.SourceSize >= 0 {
+			parts = append(parts, fmt.Sprintf("%dB", row.SourceSize))
+		}
+		if row.SourceRedirect != "" {
+			parts = append(parts, "-> "+row.SourceRedirect)
+		}
+	}
+	if len(row.SourceFiles) > 0 {
+		names := make([]string, 0, len(row.SourceFiles))
+		for _, file := range row.SourceFiles {
+			names = append(names, filepath.Base(file))
+		}
+		parts = append(parts, "["+strings.Join(names, ", ")+"]")
+	}
+	if len(parts) == 0 {
+		return "-"
+	}
+	return strings.Join(parts, " ")
+}
+
+func formatRowStatus(row ScanRow) string {
+	status := formatStatusCode(row.StatusCode)
+	if row.WildcardMatch {
+		status += " (soft-404)"
+	}
+	if row.Attempts > 1 {
+		status += fmt.Sprintf(" (%d attempts)", row.Attempts)
+	}
+	return status
+}
+
+func formatStatusCode(statusCode int) string {
+	if statusCode == 0 {
+		return "-"
+	}
+	return strconv.Itoa(statusCode)
+}
+
+func formatContentLength(row ScanRow) string {
+	if row.StatusCode == 0 {
+		return "-"
+	}
+	if row.DeclaredLength >= 0 && row.DeclaredLength != row.ContentLength {
+		return fmt.Sprintf("%d (declared %d)", row.ContentLength, row.DeclaredLength)
+	}
+	if row.BodyTruncated && row.DeclaredLength < 0 {
+		return strconv.FormatInt(row.ContentLength, 10) + "+"
+	}
+	return strconv.FormatInt(row.ContentLength, 10)
+}
+
+func formatResponseTime(row ScanRow) string {
+	if row.StatusCode == 0 && row.ResponseTimeMs == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dms", row.ResponseTimeMs)
+}
+
+func orDash(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "-"
+	}
+	return value
 }
 
 func escapeMarkdownCell(value string) string {
@@ -66,3 +360,20 @@ func escapeMarkdownCell(value string) string {
 	value = strings.ReplaceAll(value, "|", "\\|")
 	return value
 }
+
+// formatComponents lists the row's components as "WordPress 6.4 (90%)",
+// falling back to the flat view for rows without details.
+func formatComponents(row ScanRow) string {
+	if len(row.ComponentDetails) == 0 {
+		if len(row.Components) == 0 {
+			return "N/A"
+		}
+		return strings.Join(row.Components, ", ")
+	}
+
+	components := make([]string, 0, len(row.ComponentDetails))
+	for _, component := range row.ComponentDetails {
+		components = append(components, component.String())
+	}
+	return strings.Join(components, ", ")
+}
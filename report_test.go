@@ -12,16 +12,22 @@ func TestAppendMarkdownReportAppendsSectionsAndEscapesCells(t *testing.T) {
 	reportPath := filepath.Join(tempDir, "scan_report.md")
 
 	response := ScanResponse{
-		Total200Lines: 1,
-		TotalURLs:     1,
-		Succeeded:     1,
-		Failed:        0,
+		TotalMatchedLines: 1,
+		TotalURLs:         1,
+		Succeeded:         1,
+		Failed:            0,
 		Rows: []ScanRow{
 			{
-				URL:        "https://example.com/a|b",
-				Title:      "Line1\nLine2",
-				Components: []string{"Server: nginx|1.25", "WordPress"},
-				Error:      "",
+				URL:            "https://example.com/a|b",
+				FinalURL:       "https://example.com/a|b/",
+				StatusCode:     200,
+				Title:          "Line1\nLine2",
+				ContentType:    "text/html; charset=utf-8",
+				ContentLength:  512,
+				DeclaredLength: 1024,
+				ResponseTimeMs: 42,
+				Components:     []string{"Server: nginx|1.25", "WordPress"},
+				Error:          "",
 			},
 		},
 	}
@@ -55,4 +61,15 @@ func TestAppendMarkdownReportAppendsSectionsAndEscapesCells(t *testing.T) {
 	if !strings.Contains(content, "Server: nginx\\|1.25") {
 		t.Fatalf("expected escaped component pipe in report: %s", content)
 	}
+	for _, expected := range []string{
+		"<br/>-> https://example.com/a\\|b/",
+		"| 200 |",
+		"| text/html; charset=utf-8 |",
+		"| 512 (declared 1024) |",
+		"| 42ms |",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Fatalf("expected %q in report: %s", expected, content)
+		}
+	}
 }
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultRetryBackoffMs = 500
+	maxRetries            = 10
+	maxRetryDelay         = time.Minute
+)
+
+// retryHint tells scanURL whether a failed attempt is worth repeating and,
+// for 429/503 responses, how long the server asked us to wait.
+type retryHint struct {
+	retryable  bool
+	retryAfter time.Duration
+}
+
+// isTransientError reports whether err is a timeout or a dropped connection,
+// as opposed to a permanent failure such as a DNS error or a refused port.
+func isTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "connection reset by peer")
+}
+
+func isRetryableStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: a number of
+// seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// retryDelay is exponential backoff with full jitter on top of the base delay,
+// raised to the server's Retry-After when that is longer.
+func retryDelay(base time.Duration, attempt int, retryAfter time.Duration) time.Duration {
+	if base <= 0 {
+		base = defaultRetryBackoffMs * time.Millisecond
+	}
+
+	delay := base << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	delay += time.Duration(rand.Int63n(int64(base) + 1))
+
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// sleepContext waits for d and reports false if ctx was cancelled first.
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
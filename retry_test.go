@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScanURLRetriesServiceUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("<title>ok</title>"))
+	}))
+	defer server.Close()
+
+	client, _ := newHTTPClient(ScanRequest{TimeoutSeconds: 5}, nil)
+	row := scanURL(context.Background(), client, server.URL, scanOptions{maxRetries: 3, backoff: time.Millisecond})
+
+	if row.StatusCode != http.StatusOK || row.Error != "" {
+		t.Fatalf("expected final 200 without error, got %d %q", row.StatusCode, row.Error)
+	}
+	if row.Attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", row.Attempts)
+	}
+	if row.Title != "ok" {
+		t.Fatalf("expected title of the final attempt, got %q", row.Title)
+	}
+}
+
+func TestScanURLStopsAfterMaxRetries(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	client, _ := newHTTPClient(ScanRequest{TimeoutSeconds: 5}, nil)
+	row := scanURL(context.Background(), client, server.URL, scanOptions{maxRetries: 2, backoff: time.Millisecond})
+
+	if row.StatusCode != http.StatusTooManyRequests || row.Attempts != 3 || calls.Load() != 3 {
+		t.Fatalf("expected 3 attempts ending in 429, got status %d after %d attempts (%d calls)", row.StatusCode, row.Attempts, calls.Load())
+	}
+}
+
+func TestRunScanWorkersSpacesRetriesLikeRequests(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/a"), urlEntry(server.URL + "/b")}, ScanRequest{
+		Concurrency:    2,
+		TimeoutSeconds: 5,
+		PerHostDelayMs: 100,
+		MaxRetries:     2,
+		RetryBackoffMs: 1,
+		DisableFavicon: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Attempts != 3 || rows[1].Attempts != 3 {
+		t.Fatalf("expected every URL to be retried twice, got %+v", rows)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 6 {
+		t.Fatalf("expected 6 requests, got %d", len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 90*time.Millisecond {
+			t.Fatalf("expected retries to honour the 100ms per-host delay, request %d came %v after the previous one", i, gap)
+		}
+	}
+}
+
+func TestRunScanWorkersRetriesWhileOtherHostsAreQueued(t *testing.T) {
+	busy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer busy.Close()
+	idle := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer idle.Close()
+
+	// The only worker retries the busy host while the idle host's job waits
+	// to be dispatched; the retry must not wait behind it.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	rows, err := runScanWorkers(ctx, []inputEntry{urlEntry(busy.URL), urlEntry(idle.URL)}, ScanRequest{
+		Concurrency:    1,
+		TimeoutSeconds: 5,
+		MaxRetries:     1,
+		RetryBackoffMs: 1,
+		DisableFavicon: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if ctx.Err() != nil || len(rows) != 2 || rows[0].Attempts != 2 {
+		t.Fatalf("expected both rows after one retry, got %+v", rows)
+	}
+}
+
+func TestScanURLDoesNotRetryPermanentStatus(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, _ := newHTTPClient(ScanRequest{TimeoutSeconds: 5}, nil)
+	row := scanURL(context.Background(), client, server.URL, scanOptions{maxRetries: 3, backoff: time.Millisecond})
+
+	if row.Attempts != 1 || calls.Load() != 1 {
+		t.Fatalf("expected a single attempt for 404, got %d", calls.Load())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]time.Duration{
+		"":                              0,
+		"7":                             7 * time.Second,
+		"-1":                            0,
+		"Mon, 01 Jan 2024 12:00:30 GMT": 30 * time.Second,
+		"Mon, 01 Jan 2024 11:00:00 GMT": 0,
+		"soon":                          0,
+	}
+	for value, want := range cases {
+		if got := parseRetryAfter(value, now); got != want {
+			t.Fatalf("parseRetryAfter(%q) = %s, want %s", value, got, want)
+		}
+	}
+}
+
+func TestRetryDelayHonoursRetryAfterAndCap(t *testing.T) {
+	if delay := retryDelay(100*time.Millisecond, 1, 5*time.Second); delay != 5*time.Second {
+		t.Fatalf("expected Retry-After to win, got %s", delay)
+	}
+	if delay := retryDelay(100*time.Millisecond, 3, 0); delay < 400*time.Millisecond || delay > 500*time.Millisecond {
+		t.Fatalf("expected 400-500ms for the third retry, got %s", delay)
+	}
+	if delay := retryDelay(time.Second, 20, time.Hour); delay != maxRetryDelay {
+		t.Fatalf("expected delay capped at %s, got %s", maxRetryDelay, delay)
+	}
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// scanPlan is a validated ScanRequest together with the URLs it will fetch.
+// It is what the GUI and the command line share: both prepare a plan, run it
+// and then decide where the results go.
+type scanPlan struct {
+	request ScanRequest
+	entries []inputEntry
+
+	// candidates and hits replace entries in discover mode.
+	candidates discoveryCandidates
+	hits       discoveryFilter
+
+	// input, when set, replaces entries: the input report is read from it
+	// while the scan runs.
+	input        io.Reader
+	inputOptions inputOptions
+
+	// response carries the fields known before the scan starts.
+	response ScanResponse
+}
+
+// prepareScan checks every setting of a normalised request and loads its
+// entries, so configuration mistakes surface before any URL is fetched.
+func prepareScan(request ScanRequest) (*scanPlan, error) {
+	plan, filter, err := newScanPlan(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Mode == scanModeDiscover {
+		if isBatchInput(request) {
+			return nil, errors.New("\u5b57\u5178\u76ee\u5f55\u7206\u7834\u6a21\u5f0f\u53ea\u652f\u6301\u5355\u4e2a\u76ee\u6807\u6587\u4ef6")
+		}
+		if strings.TrimSpace(request.WordlistPath) == "" {
+			return nil, errors.New("\u8bf7\u9009\u62e9\u5b57\u5178\u6587\u4ef6")
+		}
+
+		sizes, err := parseSizeFilter(request.ExcludeSizes)
+		if err != nil {
+			return nil, fmt.Errorf("\u54cd\u5e94\u5927\u5c0f\u8fc7\u6ee4\u89c4\u5219\u65e0\u6548: %w", err)
+		}
+		plan.hits = discoveryFilter{status: filter, size: sizes}
+
+		plan.candidates, err = loadDiscoveryCandidates(request)
+		if err != nil {
+			return nil, err
+		}
+		plan.response.Wordlist = request.WordlistPath
+		plan.response.TotalURLs = plan.candidates.count()
+	} else if isBatchInput(request) {
+		if err := plan.loadBatchInput(); err != nil {
+			return nil, err
+		}
+	} else {
+		parsed, err := parseInputFile(request.InputFilePath, plan.inputOptions)
+		if err != nil {
+			return nil, err
+		}
+
+		plan.entries = parsed.Entries
+		plan.response.InputFormat = parsed.Format
+		plan.response.TotalMatchedLines = parsed.MatchedLines
+	}
+	if request.Mode != scanModeDiscover {
+		plan.response.TotalURLs = len(plan.entries)
+	}
+
+	return plan, nil
+}
+
+// prepareStreamScan is prepareScan for an input report read from r, such as
+// stdin. Only import mode can stream.
+func prepareStreamScan(request ScanRequest, r io.Reader) (*scanPlan, error) {
+	if request.Mode == scanModeDiscover {
+		return nil, errors.New("\u5b57\u5178\u76ee\u5f55\u7206\u7834\u6a21\u5f0f\u4e0d\u652f\u6301\u4ece\u6807\u51c6\u8f93\u5165\u8bfb\u53d6\u76ee\u6807")
+	}
+
+	plan, _, err := newScanPlan(request)
+	if err != nil {
+		return nil, err
+	}
+	plan.input = r
+	return plan, nil
+}
+
+// newScanPlan validates the settings shared by every kind of input.
+func newScanPlan(request ScanRequest) (*scanPlan, statusFilter, error) {
+	if len(batchInputPaths(request)) == 0 {
+		return nil, statusFilter{}, errors.New("\u8bf7\u8f93\u5165\u8f93\u5165\u6587\u4ef6\u8def\u5f84")
+	}
+
+	filter, err := parseStatusFilter(request.IncludeStatus, request.ExcludeStatus)
+	if err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u72b6\u6001\u7801\u8fc7\u6ee4\u89c4\u5219\u65e0\u6548: %w", err)
+	}
+
+	if _, err := newProxyFunc(request); err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u4ee3\u7406\u8bbe\u7f6e\u65e0\u6548: %w", err)
+	}
+	if request.ReplayProxyURL != "" {
+		if _, err := parseProxyURL(request.ReplayProxyURL); err != nil {
+			return nil, statusFilter{}, fmt.Errorf("\u91cd\u653e\u4ee3\u7406\u65e0\u6548: %w", err)
+		}
+	}
+
+	request.AuthRules, err = resolveAuthRules(request)
+	if err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u8ba4\u8bc1\u914d\u7f6e\u65e0\u6548: %w", err)
+	}
+	if _, err := compileAuthRules(request.AuthRules); err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u8ba4\u8bc1\u914d\u7f6e\u65e0\u6548: %w", err)
+	}
+
+	if _, err := newBrowserPicker(request.BrowserProfile); err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u6d4f\u89c8\u5668\u6307\u7eb9\u65e0\u6548: %w", err)
+	}
+
+	if _, err := newTLSConfig(request); err != nil {
+		return nil, statusFilter{}, fmt.Errorf("TLS \u8bbe\u7f6e\u65e0\u6548: %w", err)
+	}
+
+	request.fingerprints, err = newScanFingerprintEngine(splitRulePaths(request.FingerprintRulePaths))
+	if err != nil {
+		return nil, statusFilter{}, fmt.Errorf("\u6307\u7eb9\u89c4\u5219\u65e0\u6548: %w", err)
+	}
+
+	plan := &scanPlan{
+		request: request,
+		inputOptions: inputOptions{
+			Filter:  filter,
+			BaseURL: strings.TrimSpace(request.BaseURL),
+		},
+		response: ScanResponse{
+			Mode:         request.Mode,
+			StatusFilter: filter.String(),
+		},
+	}
+	return plan, filter, nil
+}
+
+// run fetches the plan's entries and post-processes the rows. onRow receives
+// every row that will appear in the results, onProgress every finished URL;
+// either may be nil. A cancelled ctx yields the partial response with
+// Cancelled set rather than an error.
+func (p *scanPlan) run(ctx context.Context, onRow func(ScanRowEvent), onProgress func(ScanProgress)) (ScanResponse, error) {
+	request := p.request
+	response := p.response
+
+	onWorkerRow := func(event ScanRowEvent, progress ScanProgress) {
+		// In discover mode only hits reach the results; misses still advance
+		// progress.
+		if onRow != nil && (request.Mode != scanModeDiscover || p.hits.Match(event.Row)) &&
+			!(request.DropWildcard && event.Row.WildcardMatch) {
+			onRow(event)
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+	}
+
+	if p.input != nil || len(p.entries) > 0 || p.candidates.count() > 0 {
+		var err error
+		switch {
+		case p.input != nil:
+			response.Rows, err = p.stream(ctx, &response, onWorkerRow)
+		case request.Mode == scanModeDiscover:
+			response.Rows, err = discoverScanWorkers(ctx, p.candidates, p.hits, request, onWorkerRow)
+		default:
+			response.Rows, err = runScanWorkers(ctx, p.entries, request, onWorkerRow)
+		}
+		if err != nil {
+			return ScanResponse{}, err
+		}
+		response.Rows, response.WildcardMatches = applyWildcardMatches(response.Rows, request.DropWildcard)
+		if request.Mode == scanModeDiscover {
+			response.TotalMatchedLines = len(response.Rows)
+		}
+		for _, row := range response.Rows {
+			if row.Error == "" {
+				response.Succeeded++
+			} else {
+				response.Failed++
+			}
+		}
+	}
+	response.Cancelled = ctx.Err() != nil
+	response.SANPivots = collectSANPivots(response.Rows)
+
+	if request.ReplayProxyURL != "" && !response.Cancelled {
+		var err error
+		response.Replayed, err = replayHits(ctx, response.Rows, request)
+		if err != nil {
+			return ScanResponse{}, err
+		}
+	}
+
+	return response, nil
+}
+
+// stream scans the entries of p.input as they are parsed and fills in the
+// input statistics of response once the input is exhausted.
+func (p *scanPlan) stream(ctx context.Context, response *ScanResponse, onProgress scanProgressFunc) ([]ScanRow, error) {
+	type streamResult struct {
+		parsed inputParseResult
+		urls   int
+		err    error
+	}
+
+	entries := make(chan inputEntry)
+	parsed := make(chan streamResult, 1)
+	go func() {
+		result, urls, err := streamInput(ctx, p.input, p.inputOptions, entries)
+		parsed <- streamResult{parsed: result, urls: urls, err: err}
+	}()
+
+	rows, err := streamScanWorkers(ctx, entries, p.request, onProgress)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case result := <-parsed:
+		if result.err != nil {
+			return nil, result.err
+		}
+		response.InputFormat = result.parsed.Format
+		response.TotalMatchedLines = result.parsed.MatchedLines
+		response.TotalURLs = result.urls
+	case <-ctx.Done():
+		// The reader may still be blocked on input that never comes.
+		response.TotalURLs = len(rows)
+	}
+	return rows, nil
+}
+
+// reportDir is where reports for request go: the configured output directory,
+// or the directory of the input file.
+func reportDir(request ScanRequest) string {
+	outputDir := strings.TrimSpace(request.OutputDir)
+	if outputDir == "" {
+		outputDir = filepath.Dir(request.InputFilePath)
+	}
+	return outputDir
+}
+
+// finishScan appends the Markdown report for a finished scan and, when asked
+// to and the scan was not cancelled, removes the input files. A batch scan
+// gets one merged report, or one report per input file with ReportPerSource.
+// Streamed input has no file to remove.
+func finishScan(request ScanRequest, response *ScanResponse) error {
+	response.ReportPaths = nil
+	if request.ReportPerSource && len(response.InputFiles) > 0 {
+		for _, source := range response.InputFiles {
+			sourceRequest := request
+			sourceRequest.InputFilePath = source.Path
+			reportPath := filepath.Join(reportDir(sourceRequest), buildReportFileName(source.Path))
+			if err := appendMarkdownReport(reportPath, source.Path, sourceResponse(*response, source)); err != nil {
+				return err
+			}
+			response.ReportPaths = append(response.ReportPaths, reportPath)
+		}
+	} else {
+		reportPath := filepath.Join(reportDir(request), buildReportFileName(request.InputFilePath))
+		if err := appendMarkdownReport(reportPath, request.InputFilePath, *response); err != nil {
+			return err
+		}
+		response.ReportPaths = []string{reportPath}
+	}
+	response.ReportPath = response.ReportPaths[0]
+
+	if !request.DeleteSourceAfterRun || response.Cancelled || request.InputFilePath == stdinInputPath {
+		return nil
+	}
+	sources := []string{request.InputFilePath}
+	if len(response.InputFiles) > 0 {
+		sources = sources[:0]
+		for _, source := range response.InputFiles {
+			sources = append(sources, source.Path)
+		}
+	}
+	for _, source := range sources {
+		if err := removeInputFile(source); err != nil {
+			return fmt.Errorf("\u5220\u9664\u6e90\u6587\u4ef6\u5931\u8d25: %w", err)
+		}
+	}
+	return nil
+}
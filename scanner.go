@@ -1,15 +1,20 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"net"
 	"net/http"
-	"os"
-	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/net/html"
@@ -20,80 +25,194 @@ const (
 	maxBodySize      = 2 << 20
 )
 
-var (
-	statusMatchRegex = regexp.MustCompile(`^\s*(200|301|403)\b`)
-	urlRegex         = regexp.MustCompile(`https?://[^\s"'<>]+`)
-)
-
-type indexedURL struct {
+type indexedEntry struct {
 	Index int
-	URL   string
+	Entry inputEntry
+	Host  string
 }
 
 type indexedRow struct {
 	Index int
 	Row   ScanRow
+	// Miss marks a discover-mode row that only counts towards progress.
+	Miss bool
+}
+
+// scanOptions is the per-URL behaviour derived once from a ScanRequest and
+// shared by every worker.
+type scanOptions struct {
+	maxRetries   int
+	backoff      time.Duration
+	auth         authRules
+	browsers     browserPicker
+	scope        *scanScope
+	fingerprints *fingerprintEngine
+
+	// scheduler paces follow-up requests such as retries; nil lets them
+	// through.
+	scheduler *hostScheduler
 }
 
-func parseInputFile(path string) ([]string, int, error) {
-	file, err := os.Open(path)
+func newScanOptions(request ScanRequest) (scanOptions, error) {
+	auth, err := compileAuthRules(request.AuthRules)
 	if err != nil {
-		return nil, 0, fmt.Errorf("open input file: %w", err)
+		return scanOptions{}, err
 	}
-	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, 1024), 1024*1024)
-
-	seen := make(map[string]struct{})
-	urls := make([]string, 0)
-	totalMatchedLines := 0
+	browsers, err := newBrowserPicker(request.BrowserProfile)
+	if err != nil {
+		return scanOptions{}, err
+	}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if !statusMatchRegex.MatchString(line) {
-			continue
+	fingerprints := request.fingerprints
+	if fingerprints == nil {
+		fingerprints, err = newScanFingerprintEngine(splitRulePaths(request.FingerprintRulePaths))
+		if err != nil {
+			return scanOptions{}, err
 		}
+	}
 
-		totalMatchedLines++
-		matched := urlRegex.FindString(line)
-		if matched == "" {
-			continue
-		}
+	return scanOptions{
+		maxRetries: request.MaxRetries,
+		backoff:    time.Duration(request.RetryBackoffMs) * time.Millisecond,
+		auth:       auth,
+		browsers:   browsers,
 
-		if _, ok := seen[matched]; ok {
-			continue
-		}
+		fingerprints: fingerprints,
+	}, nil
+}
 
-		seen[matched] = struct{}{}
-		urls = append(urls, matched)
+// scanProgressFunc receives every finished row together with the aggregate
+// progress of the scan. It is called from a single goroutine.
+type scanProgressFunc func(event ScanRowEvent, progress ScanProgress)
+
+// runScanWorkers fetches every entry with a bounded worker pool fed by a
+// hostScheduler, which applies the request's rate limits. When ctx is
+// cancelled no further URLs are dispatched, in-flight requests are aborted and
+// only the rows that completed are returned, in input order. onProgress may be
+// nil. An error is only returned when the HTTP client cannot be configured.
+func runScanWorkers(ctx context.Context, entries []inputEntry, request ScanRequest, onProgress scanProgressFunc) ([]ScanRow, error) {
+	if len(entries) == 0 {
+		return nil, nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, 0, fmt.Errorf("read input file: %w", err)
+	total := &atomic.Int64{}
+	total.Store(int64(len(entries)))
+	return scanEntries(ctx, feedEntries(ctx, slices.Values(entries)), total, newScanScope(entries), request, nil, onProgress)
+}
+
+// discoverScanWorkers is runScanWorkers for the candidates of a discover
+// scan. Only the hits are returned; misses advance progress but skip the
+// follow-up requests and fingerprinting.
+func discoverScanWorkers(ctx context.Context, candidates discoveryCandidates, hits discoveryFilter, request ScanRequest, onProgress scanProgressFunc) ([]ScanRow, error) {
+	scope := newScanScope(nil)
+	for _, target := range candidates.targets {
+		scope.add(target)
 	}
 
-	return urls, totalMatchedLines, nil
+	total := &atomic.Int64{}
+	total.Store(int64(candidates.count()))
+	return scanEntries(ctx, feedEntries(ctx, candidates.entries()), total, scope, request, hits.Match, onProgress)
+}
+
+// feedEntries numbers the entries of seq and sends them on the returned
+// channel as the scheduler takes them, until seq ends or ctx is cancelled.
+func feedEntries(ctx context.Context, seq iter.Seq[inputEntry]) <-chan indexedEntry {
+	incoming := make(chan indexedEntry)
+	go func() {
+		defer close(incoming)
+		index := 0
+		for entry := range seq {
+			select {
+			case incoming <- indexedEntry{Index: index, Entry: entry, Host: hostKey(entry.URL)}:
+				index++
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return incoming
+}
+
+// streamScanWorkers is runScanWorkers for entries that arrive while the scan
+// runs, e.g. from stdin; it returns once entries is closed and drained. The
+// progress total counts the entries received so far, and redirects are only
+// flagged off-scope against the hosts seen so far.
+func streamScanWorkers(ctx context.Context, entries <-chan inputEntry, request ScanRequest, onProgress scanProgressFunc) ([]ScanRow, error) {
+	total := &atomic.Int64{}
+	scope := newScanScope(nil)
+	incoming := make(chan indexedEntry)
+
+	go func() {
+		defer close(incoming)
+		for index := 0; ; index++ {
+			var (
+				entry inputEntry
+				ok    bool
+			)
+			select {
+			case entry, ok = <-entries:
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				return
+			}
+
+			scope.add(entry.URL)
+			total.Add(1)
+			select {
+			case incoming <- indexedEntry{Index: index, Entry: entry, Host: hostKey(entry.URL)}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return scanEntries(ctx, incoming, total, scope, request, nil, onProgress)
 }
 
-func runScanWorkers(urls []string, request ScanRequest) []ScanRow {
-	if len(urls) == 0 {
-		return nil
+// scanEntries runs the worker pool over incoming. total is the number of
+// entries known so far and may grow while the scan runs. keep, when set,
+// decides which rows are collected; the others are only counted.
+func scanEntries(ctx context.Context, incoming <-chan indexedEntry, total *atomic.Int64, scope *scanScope, request ScanRequest, keep func(ScanRow) bool, onProgress scanProgressFunc) ([]ScanRow, error) {
+	options, err := newScanOptions(request)
+	if err != nil {
+		return nil, err
+	}
+	options.scope = scope
+
+	client, err := newHTTPClient(request, options.auth)
+	if err != nil {
+		return nil, err
 	}
+	defer client.CloseIdleConnections()
 
 	concurrency := request.Concurrency
 	if concurrency <= 0 {
 		concurrency = defaultConcurrency
 	}
-	if concurrency > len(urls) {
-		concurrency = len(urls)
+	if known := int(total.Load()); known > 0 && concurrency > known {
+		concurrency = known
 	}
 
-	results := make([]ScanRow, len(urls))
-	jobs := make(chan indexedURL)
+	scheduler := newHostScheduler(request)
+	scheduler.workers = concurrency
+	options.scheduler = scheduler
+
+	jobs := make(chan indexedEntry)
+	done := make(chan string, concurrency)
 	out := make(chan indexedRow)
 
-	client := newHTTPClient(request)
+	var wildcards *wildcardDetector
+	if request.DetectWildcard {
+		wildcards = newWildcardDetector(client, options)
+	}
+
+	var favicons *faviconFetcher
+	if !request.DisableFavicon {
+		favicons = newFaviconFetcher(client, options)
+	}
 
 	var wg sync.WaitGroup
 	for i := 0; i < concurrency; i++ {
@@ -101,77 +220,221 @@ func runScanWorkers(urls []string, request ScanRequest) []ScanRow {
 		go func() {
 			defer wg.Done()
 			for job := range jobs {
-				out <- indexedRow{Index: job.Index, Row: scanURL(client, job.URL)}
+				row := scanURL(ctx, client, job.Entry.URL, options)
+				// Discover-mode misses are only counted, so they skip the
+				// follow-up requests and fingerprinting.
+				miss := keep != nil && !keep(row)
+				// Follow-up requests to the host count towards its limits, so
+				// the scheduler only hears about the job once they are done.
+				if !miss && wildcards != nil && ctx.Err() == nil {
+					row.WildcardMatch = wildcards.Matches(ctx, row)
+				}
+				if !miss && favicons != nil && ctx.Err() == nil {
+					favicons.Annotate(ctx, &row)
+				}
+				request.gate.release()
+				if !miss {
+					identifyComponents(&row, options.fingerprints)
+				}
+				done <- job.Host
+				if errors.Is(row.fetchErr, context.Canceled) {
+					// The request was aborted by cancellation, not by the target.
+					continue
+				}
+				if miss {
+					row.evidence = nil
+					out <- indexedRow{Index: job.Index, Row: row, Miss: true}
+					continue
+				}
+				row.SourceStatus = job.Entry.Status
+				row.SourceSize = job.Entry.Size
+				row.SourceRedirect = job.Entry.Redirect
+				row.SourceFiles = job.Entry.Sources
+				out <- indexedRow{Index: job.Index, Row: row}
 			}
 		}()
 	}
 
 	go func() {
-		for i, url := range urls {
-			jobs <- indexedURL{Index: i, URL: url}
+		scheduler.run(ctx, incoming, jobs, done)
+		// Workers still report the jobs that were in flight.
+		for range done {
 		}
-		close(jobs)
 	}()
 
 	go func() {
 		wg.Wait()
+		close(done)
 		close(out)
 	}()
 
+	var collected []indexedRow
+	progress := ScanProgress{}
+	startedAt := time.Now()
 	for item := range out {
-		results[item.Index] = item.Row
+		if !item.Miss {
+			collected = append(collected, item)
+		}
+
+		if onProgress == nil {
+			continue
+		}
+		progress.Total = int(total.Load())
+		progress.Done++
+		if item.Row.Error == "" {
+			progress.Succeeded++
+		} else {
+			progress.Failed++
+		}
+		updateThroughput(&progress, time.Since(startedAt))
+		onProgress(ScanRowEvent{Index: item.Index, Row: item.Row}, progress)
 	}
 
-	return results
+	slices.SortFunc(collected, func(a, b indexedRow) int { return a.Index - b.Index })
+	rows := make([]ScanRow, 0, len(collected))
+	for _, item := range collected {
+		rows = append(rows, item.Row)
+	}
+	return rows, nil
 }
 
-func newHTTPClient(request ScanRequest) *http.Client {
+func updateThroughput(progress *ScanProgress, elapsed time.Duration) {
+	if elapsed <= 0 || progress.Done == 0 {
+		return
+	}
+
+	progress.RatePerSecond = float64(progress.Done) / elapsed.Seconds()
+	remaining := progress.Total - progress.Done
+	progress.ETASeconds = int(float64(remaining)/progress.RatePerSecond + 0.5)
+}
+
+// newHTTPClient builds the scan's client; auth is re-applied on every
+// redirect hop.
+func newHTTPClient(request ScanRequest, auth authRules) (*http.Client, error) {
 	timeoutSeconds := request.TimeoutSeconds
 	if timeoutSeconds <= 0 {
 		timeoutSeconds = defaultTimeoutSecond
 	}
 
-	client := &http.Client{Timeout: time.Duration(timeoutSeconds) * time.Second}
-	if !request.FollowRedirect {
-		client.CheckRedirect = func(*http.Request, []*http.Request) error {
-			return http.ErrUseLastResponse
-		}
+	proxy, err := newProxyFunc(request)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := newTLSConfig(request)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.TLSClientConfig = tlsConfig
+
+	client := &http.Client{
+		Timeout:       time.Duration(timeoutSeconds) * time.Second,
+		Transport:     transport,
+		CheckRedirect: checkRedirect(request.FollowRedirect, auth),
 	}
 
-	return client
+	return client, nil
 }
 
-func scanURL(client *http.Client, targetURL string) ScanRow {
+// scanURL fetches targetURL, retrying timeouts, dropped connections and
+// 429/503 responses up to options.maxRetries times. Retries wait for the
+// scheduler like any other request to the host. Only the final attempt is
+// returned; Attempts records how many were made. Every attempt uses the same
+// browser profile.
+func scanURL(ctx context.Context, client *http.Client, targetURL string, options scanOptions) ScanRow {
+	browser := options.browsers.pick()
+	for attempt := 1; ; attempt++ {
+		row, hint := fetchURL(ctx, client, targetURL, browser, options.auth)
+		row.Attempts = attempt
+		done := !hint.retryable || attempt > options.maxRetries || ctx.Err() != nil
+		if done || !sleepContext(ctx, retryDelay(options.backoff, attempt, hint.retryAfter)) ||
+			options.scheduler.wait(ctx, targetURL) != nil {
+			flagRedirects(&row, options.scope)
+			return row
+		}
+	}
+}
+
+func fetchURL(ctx context.Context, client *http.Client, targetURL string, browser browserProfile, auth authRules) (ScanRow, retryHint) {
 	row := ScanRow{
-		URL:        targetURL,
-		Title:      "N/A",
-		Components: []string{"N/A"},
+		URL:            targetURL,
+		BrowserProfile: browser.name,
+		Title:          "N/A",
+		Components:     []string{"N/A"},
+		DeclaredLength: -1,
 	}
 
-	req, err := http.NewRequest(http.MethodGet, targetURL, nil)
+	requestCtx, chain := withRedirectChain(ctx)
+	req, err := http.NewRequestWithContext(requestCtx, http.MethodGet, targetURL, nil)
 	if err != nil {
 		row.Error = err.Error()
-		return row
+		return row, retryHint{}
+	}
+	for _, header := range browser.headers {
+		req.Header.Set(header.name, header.value)
 	}
-	req.Header.Set("User-Agent", defaultUserAgent)
+	auth.apply(req)
 
+	startedAt := time.Now()
 	resp, err := client.Do(req)
+	row.RedirectChain = *chain
 	if err != nil {
+		row.ResponseTimeMs = time.Since(startedAt).Milliseconds()
 		row.Error = err.Error()
-		return row
+		row.fetchErr = err
+		row.TLS = inspectCertificateError(err, time.Now())
+		return row, retryHint{retryable: isTransientError(err)}
 	}
 	defer resp.Body.Close()
 
-	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+	row.StatusCode = resp.StatusCode
+	row.FinalURL = resp.Request.URL.String()
+	row.ContentType = resp.Header.Get("Content-Type")
+	row.DeclaredLength = resp.ContentLength
+	row.Location = resp.Header.Get("Location")
+	row.TLS = inspectTLS(resp.TLS, resp.Request.URL.Hostname(), time.Now())
+
+	// Only the first maxBodySize bytes are read. A longer body, or one the
+	// client timeout cut short after the headers arrived, is marked truncated
+	// and takes its length from Content-Length when the server sent one.
+	body, readErr := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if len(body) > maxBodySize {
+		body = body[:maxBodySize]
+		row.BodyTruncated = true
+	}
+	var netErr net.Error
+	if errors.As(readErr, &netErr) && netErr.Timeout() {
+		row.BodyTruncated, readErr = true, nil
+	}
+	row.ContentLength = int64(len(body))
+	if row.BodyTruncated && resp.ContentLength >= 0 {
+		row.ContentLength = resp.ContentLength
+	}
+	row.ResponseTimeMs = time.Since(startedAt).Milliseconds()
 	if readErr != nil {
 		row.Error = readErr.Error()
+		row.fetchErr = readErr
 	}
 
-	title, generator := extractHTMLSignals(body)
-	if title != "" {
-		row.Title = title
+	row.Words = len(bytes.Fields(body))
+	bodySum := sha1.Sum(body)
+	row.bodyHash = hex.EncodeToString(bodySum[:])
+
+	signals := extractHTMLSignals(body)
+	if signals.Title != "" {
+		row.Title = signals.Title
+	}
+	row.ComponentDetails = headerComponents(resp, signals.Generator)
+	row.Components = componentLabels(row.ComponentDetails)
+	row.evidence = newFingerprintEvidence(resp, body, signals)
+	if len(signals.Icons) > 0 {
+		if iconURL, err := resp.Request.URL.Parse(signals.Icons[0]); err == nil {
+			row.pageIconURL = iconURL.String()
+		}
 	}
-	row.Components = extractComponents(resp, body, generator)
 
 	if resp.StatusCode >= http.StatusBadRequest {
 		if row.Error == "" {
@@ -181,46 +444,62 @@ func scanURL(client *http.Client, targetURL string) ScanRow {
 		}
 	}
 
-	return row
+	hint := retryHint{retryable: isTransientError(readErr)}
+	if isRetryableStatus(resp.StatusCode) {
+		hint = retryHint{retryable: true, retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())}
+	}
+
+	return row, hint
+}
+
+// htmlSignals is what the scanner reads from an HTML page: the title, the
+// <meta> tags by lower-cased name, <script src> URLs and the hrefs of
+// <link rel="icon"> elements.
+type htmlSignals struct {
+	Title     string
+	Generator string
+	Meta      map[string][]string
+	Scripts   []string
+	Icons     []string
 }
 
-func extractHTMLSignals(body []byte) (string, string) {
+func extractHTMLSignals(body []byte) htmlSignals {
+	signals := htmlSignals{Meta: make(map[string][]string)}
 	if len(body) == 0 {
-		return "", ""
+		return signals
 	}
 
 	tokenizer := html.NewTokenizer(bytes.NewReader(body))
 	inTitle := false
 	titleBuilder := strings.Builder{}
-	generator := ""
 
 	for {
 		tokenType := tokenizer.Next()
 		switch tokenType {
 		case html.ErrorToken:
-			return strings.TrimSpace(html.UnescapeString(titleBuilder.String())), strings.TrimSpace(html.UnescapeString(generator))
+			signals.Title = strings.TrimSpace(html.UnescapeString(titleBuilder.String()))
+			signals.Generator = strings.TrimSpace(html.UnescapeString(signals.Generator))
+			return signals
 		case html.StartTagToken, html.SelfClosingTagToken:
 			token := tokenizer.Token()
-			if strings.EqualFold(token.Data, "title") {
+			switch {
+			case strings.EqualFold(token.Data, "title"):
 				inTitle = true
-				continue
-			}
-			if !strings.EqualFold(token.Data, "meta") {
-				continue
-			}
-
-			name := ""
-			content := ""
-			for _, attr := range token.Attr {
-				if strings.EqualFold(attr.Key, "name") {
-					name = strings.TrimSpace(attr.Val)
+			case strings.EqualFold(token.Data, "meta"):
+				if name, content := metaNameContent(token); name != "" && content != "" {
+					signals.Meta[name] = append(signals.Meta[name], content)
+					if name == "generator" && signals.Generator == "" {
+						signals.Generator = content
+					}
 				}
-				if strings.EqualFold(attr.Key, "content") {
-					content = strings.TrimSpace(attr.Val)
+			case strings.EqualFold(token.Data, "script"):
+				if src := attrValue(token, "src"); src != "" {
+					signals.Scripts = append(signals.Scripts, src)
+				}
+			case strings.EqualFold(token.Data, "link"):
+				if href := iconHref(token); href != "" {
+					signals.Icons = append(signals.Icons, href)
 				}
-			}
-			if strings.EqualFold(name, "generator") && content != "" {
-				generator = content
 			}
 		case html.TextToken:
 			if inTitle {
@@ -235,72 +514,65 @@ func extractHTMLSignals(body []byte) (string, string) {
 	}
 }
 
-func extractComponents(resp *http.Response, body []byte, generator string) []string {
-	components := make([]string, 0)
-	seen := make(map[string]struct{})
-
-	for _, key := range []string{"Server", "X-Powered-By", "Via", "X-AspNet-Version", "X-AspNetMvc-Version"} {
-		value := strings.TrimSpace(resp.Header.Get(key))
-		if value == "" {
-			continue
-		}
-		addUniqueComponent(&components, seen, fmt.Sprintf("%s: %s", key, value))
+// metaNameContent returns the lower-cased name (or property / http-equiv)
+// of a <meta> tag and its content.
+func metaNameContent(token html.Token) (string, string) {
+	name := attrValue(token, "name")
+	if name == "" {
+		name = attrValue(token, "property")
 	}
-
-	if generator != "" {
-		addUniqueComponent(&components, seen, fmt.Sprintf("Meta Generator: %s", generator))
+	if name == "" {
+		name = attrValue(token, "http-equiv")
 	}
+	return strings.ToLower(name), attrValue(token, "content")
+}
 
-	lowerBody := strings.ToLower(string(body))
-	detectBodyComponents(lowerBody, &components, seen)
-
-	if len(components) == 0 {
-		return []string{"N/A"}
+func attrValue(token html.Token, key string) string {
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, key) {
+			return strings.TrimSpace(attr.Val)
+		}
 	}
-
-	return components
+	return ""
 }
 
-func detectBodyComponents(body string, components *[]string, seen map[string]struct{}) {
-	type marker struct {
-		contains []string
-		name     string
-	}
-
-	markers := []marker{
-		{contains: []string{"wp-content", "wordpress"}, name: "WordPress"},
-		{contains: []string{"drupal-settings-json", "drupal"}, name: "Drupal"},
-		{contains: []string{"content=\"joomla", "joomla!"}, name: "Joomla"},
-		{contains: []string{"__next", "next.js"}, name: "Next.js"},
-		{contains: []string{"__nuxt", "nuxt"}, name: "Nuxt"},
-		{contains: []string{"reactroot", "data-reactroot", "react-dom"}, name: "React"},
-		{contains: []string{"data-v-", "vue.js", "vue.runtime"}, name: "Vue"},
-		{contains: []string{"__viewstate", "asp.net"}, name: "ASP.NET"},
-		{contains: []string{".php", "<?php", "php/"}, name: "PHP"},
-		{contains: []string{"jsessionid", "java servlet", "jsp"}, name: "Java"},
-	}
-
-	for _, marker := range markers {
-		for _, token := range marker.contains {
-			if strings.Contains(body, token) {
-				addUniqueComponent(components, seen, marker.name)
-				break
+// iconHref returns the href of <link rel="icon">, "shortcut icon" and
+// "apple-touch-icon" elements.
+func iconHref(token html.Token) string {
+	isIcon := false
+	href := ""
+	for _, attr := range token.Attr {
+		if strings.EqualFold(attr.Key, "rel") {
+			for _, rel := range strings.Fields(strings.ToLower(attr.Val)) {
+				if rel == "icon" || rel == "apple-touch-icon" {
+					isIcon = true
+				}
 			}
 		}
+		if strings.EqualFold(attr.Key, "href") {
+			href = strings.TrimSpace(attr.Val)
+		}
 	}
-}
-
-func addUniqueComponent(components *[]string, seen map[string]struct{}, value string) {
-	trimmed := strings.TrimSpace(value)
-	if trimmed == "" {
-		return
+	if !isIcon || strings.HasPrefix(href, "data:") {
+		return ""
 	}
+	return href
+}
 
-	key := strings.ToLower(trimmed)
-	if _, ok := seen[key]; ok {
-		return
+// identifyComponents adds the products recognised by fingerprint rules to
+// the row's components and releases the evidence.
+func identifyComponents(row *ScanRow, engine *fingerprintEngine) {
+	if engine != nil {
+		for _, match := range engine.Identify(row.evidence, *row) {
+			row.ComponentDetails = mergeComponent(row.ComponentDetails, Component{
+				Name:       match.name,
+				Version:    match.version,
+				Category:   strings.Join(match.categories, ", "),
+				Confidence: match.confidence,
+				Evidence:   match.evidence,
+			})
+		}
+		row.Components = componentLabels(row.ComponentDetails)
 	}
-
-	seen[key] = struct{}{}
-	*components = append(*components, trimmed)
+	row.evidence = nil
 }
@@ -1,58 +1,14 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
-	"os"
-	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
-func TestParseInputFileDirsearchStyle(t *testing.T) {
-	tempDir := t.TempDir()
-	inputPath := filepath.Join(tempDir, "input.txt")
-
-	content := strings.Join([]string{
-		"200 123B 0.001s http://example.com/a",
-		"301 123B 0.001s http://example.com/b",
-		"403 123B 0.001s https://example.org/blocked",
-		"404 123B 0.001s http://example.com/ignore",
-		"  200 88B 0.003s https://example.org/login?x=1",
-		"403 no-url-here",
-		"301 duplicate http://example.com/b",
-	}, "\n")
-
-	if err := os.WriteFile(inputPath, []byte(content), 0o644); err != nil {
-		t.Fatalf("write input file: %v", err)
-	}
-
-	urls, totalMatched, err := parseInputFile(inputPath)
-	if err != nil {
-		t.Fatalf("parse input file: %v", err)
-	}
-
-	if totalMatched != 6 {
-		t.Fatalf("expected total matched lines=6, got %d", totalMatched)
-	}
-
-	expected := []string{
-		"http://example.com/a",
-		"http://example.com/b",
-		"https://example.org/blocked",
-		"https://example.org/login?x=1",
-	}
-	if len(urls) != len(expected) {
-		t.Fatalf("expected %d urls, got %d: %#v", len(expected), len(urls), urls)
-	}
-
-	for i := range expected {
-		if urls[i] != expected[i] {
-			t.Fatalf("expected url[%d]=%s, got %s", i, expected[i], urls[i])
-		}
-	}
-}
-
 func TestRunScanWorkersExtractsSignalsAndMarksFailures(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.URL.Path {
@@ -69,11 +25,14 @@ func TestRunScanWorkersExtractsSignalsAndMarksFailures(t *testing.T) {
 	}))
 	defer server.Close()
 
-	rows := runScanWorkers([]string{server.URL + "/ok", server.URL + "/bad"}, ScanRequest{
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/ok"), urlEntry(server.URL + "/bad")}, ScanRequest{
 		Concurrency:    2,
 		TimeoutSeconds: 5,
 		FollowRedirect: true,
-	})
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
 
 	if len(rows) != 2 {
 		t.Fatalf("expected 2 rows, got %d", len(rows))
@@ -84,15 +43,28 @@ func TestRunScanWorkersExtractsSignalsAndMarksFailures(t *testing.T) {
 	}
 
 	components := strings.Join(rows[0].Components, " | ")
-	for _, expected := range []string{"X-Powered-By: PHP/8.2", "Meta Generator: WordPress 6.0", "WordPress", "Next.js"} {
+	for _, expected := range []string{"PHP 8.2", "WordPress 6.0", "Next.js"} {
 		if !strings.Contains(components, expected) {
 			t.Fatalf("expected component %q in %q", expected, components)
 		}
 	}
+	wordpress := rows[0].ComponentDetails[1]
+	if wordpress.Name != "WordPress" || wordpress.Version != "6.0" || wordpress.Category != "CMS" || wordpress.Confidence != 100 || wordpress.Evidence != "meta generator: WordPress 6.0" {
+		t.Fatalf("unexpected WordPress details: %+v", wordpress)
+	}
 
 	if rows[0].Error != "" {
 		t.Fatalf("expected no error for ok row, got %q", rows[0].Error)
 	}
+	if rows[0].StatusCode != http.StatusOK || rows[0].FinalURL != server.URL+"/ok" {
+		t.Fatalf("unexpected status/final URL: %d %s", rows[0].StatusCode, rows[0].FinalURL)
+	}
+	if !strings.HasPrefix(rows[0].ContentType, "text/html") {
+		t.Fatalf("expected html content type, got %q", rows[0].ContentType)
+	}
+	if rows[0].ContentLength == 0 || rows[0].ContentLength != rows[0].DeclaredLength {
+		t.Fatalf("expected matching body lengths, got actual=%d declared=%d", rows[0].ContentLength, rows[0].DeclaredLength)
+	}
 
 	if rows[1].Error == "" {
 		t.Fatalf("expected error for bad row")
@@ -100,4 +72,135 @@ func TestRunScanWorkersExtractsSignalsAndMarksFailures(t *testing.T) {
 	if !strings.Contains(rows[1].Error, "HTTP 500") {
 		t.Fatalf("expected HTTP 500 in error, got %q", rows[1].Error)
 	}
+	if rows[1].StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", rows[1].StatusCode)
+	}
+}
+
+func TestRunScanWorkersReportsProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bad" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("<title>ok</title>"))
+	}))
+	defer server.Close()
+
+	urls := []string{server.URL + "/a", server.URL + "/bad", server.URL + "/c"}
+	entries := make([]inputEntry, 0, len(urls))
+	for _, url := range urls {
+		entries = append(entries, urlEntry(url))
+	}
+	seen := make(map[int]string)
+	var last ScanProgress
+	rows, err := runScanWorkers(context.Background(), entries, ScanRequest{Concurrency: 2, TimeoutSeconds: 5}, func(event ScanRowEvent, progress ScanProgress) {
+		seen[event.Index] = event.Row.URL
+		if progress.Done != len(seen) {
+			t.Errorf("expected done=%d, got %d", len(seen), progress.Done)
+		}
+		last = progress
+	})
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	if len(rows) != len(urls) || len(seen) != len(urls) {
+		t.Fatalf("expected an event per row, got %d events for %d rows", len(seen), len(rows))
+	}
+	for i, url := range urls {
+		if seen[i] != url {
+			t.Fatalf("expected event index %d to carry %s, got %s", i, url, seen[i])
+		}
+	}
+	if last.Total != 3 || last.Done != 3 || last.Succeeded != 2 || last.Failed != 1 || last.ETASeconds != 0 {
+		t.Fatalf("unexpected final progress: %+v", last)
+	}
+	if last.RatePerSecond <= 0 {
+		t.Fatalf("expected positive throughput, got %v", last.RatePerSecond)
+	}
+}
+
+func TestStreamScanWorkersScansEntriesAsTheyArrive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<title>" + r.URL.Path + "</title>"))
+	}))
+	defer server.Close()
+
+	entries := make(chan inputEntry)
+	progressed := make(chan ScanProgress, 2)
+	type scanResult struct {
+		rows []ScanRow
+		err  error
+	}
+	done := make(chan scanResult, 1)
+	go func() {
+		rows, err := streamScanWorkers(context.Background(), entries, ScanRequest{Concurrency: 4, TimeoutSeconds: 5, DisableFavicon: true}, func(_ ScanRowEvent, progress ScanProgress) {
+			progressed <- progress
+		})
+		done <- scanResult{rows, err}
+	}()
+
+	entries <- urlEntry(server.URL + "/first")
+	select {
+	case progress := <-progressed:
+		if progress.Done != 1 || progress.Total != 1 {
+			t.Fatalf("unexpected progress %+v", progress)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the first entry to be scanned before the input ended")
+	}
+
+	entries <- urlEntry(server.URL + "/second")
+	close(entries)
+	result := <-done
+	if result.err != nil {
+		t.Fatalf("streamScanWorkers returned error: %v", result.err)
+	}
+	if len(result.rows) != 2 || result.rows[0].Title != "/first" || result.rows[1].Title != "/second" {
+		t.Fatalf("expected both rows in arrival order, got %+v", result.rows)
+	}
+	if last := <-progressed; last.Done != 2 || last.Total != 2 {
+		t.Fatalf("expected the total to grow with the input, got %+v", last)
+	}
+}
+
+func TestFetchURLStopsReadingLargeBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := make([]byte, 64<<10)
+		switch r.URL.Path {
+		case "/endless":
+			for r.Context().Err() == nil {
+				if _, err := w.Write(chunk); err != nil {
+					return
+				}
+			}
+		case "/stalled":
+			w.Header().Set("Content-Length", "10000000")
+			_, _ = w.Write(chunk)
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	options := scanOptions{maxRetries: 2, backoff: time.Millisecond}
+	client, err := newHTTPClient(ScanRequest{TimeoutSeconds: 1}, nil)
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+
+	startedAt := time.Now()
+	row := scanURL(context.Background(), client, server.URL+"/endless", options)
+	if row.Error != "" || row.Attempts != 1 || !row.BodyTruncated || row.ContentLength != maxBodySize {
+		t.Fatalf("expected an endless body to be cut at %d bytes, got %+v", maxBodySize, row)
+	}
+	if elapsed := time.Since(startedAt); elapsed >= time.Second {
+		t.Fatalf("expected the read to stop before the timeout, took %v", elapsed)
+	}
+
+	row = scanURL(context.Background(), client, server.URL+"/stalled", options)
+	if row.Error != "" || row.Attempts != 1 || !row.BodyTruncated || row.StatusCode != http.StatusOK || row.ContentLength != 10000000 {
+		t.Fatalf("expected a body cut off by the timeout to keep the declared length without a retry, got %+v", row)
+	}
 }
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// pendingPerWorker bounds the scheduler's queue per worker.
+const pendingPerWorker = 16
+
+// hostScheduler sits between the parsed input and the worker pool. It keeps a
+// queue per host and hands jobs out round-robin across hosts, so a file
+// dominated by one host neither starves the others nor sends that host more
+// than the configured politeness allows:
+//
+//   - requestsPerSecond caps dispatches across all hosts;
+//   - perHostLimit caps in-flight requests per host;
+//   - delay (plus a random jitter) spaces consecutive requests to one host.
+//
+// A zero value for any of these disables that limit. Follow-up requests that
+// workers make for a job they are running, such as retries, go through wait
+// and honour the rate and delay limits too.
+//
+// A job is only handed out once the request's gate lets it through, so the
+// limits are applied when the request is really sent rather than before a
+// pause or a wait for the shared budget.
+//
+// At most pendingLimit jobs are queued; the scheduler stops reading its input
+// until they drain, so lazily generated or streamed input is only produced as
+// fast as it is scanned.
+type hostScheduler struct {
+	interval     time.Duration
+	perHostLimit int
+	delay        time.Duration
+	jitter       time.Duration
+
+	gate *scanGate
+	// workers is the size of the worker pool; a budget slot is only taken
+	// while a worker is free to use it. Zero means unknown.
+	workers int
+
+	queues    map[string][]indexedEntry
+	followUps map[string][]chan struct{}
+	hosts     []string
+	next      int
+	inflight  map[string]int
+	readyAt   map[string]time.Time
+	globalAt  time.Time
+
+	requests chan followUp
+}
+
+// followUp asks the scheduler for a request to host; ready is closed once
+// the limits allow it.
+type followUp struct {
+	host  string
+	ready chan struct{}
+}
+
+func newHostScheduler(request ScanRequest) *hostScheduler {
+	scheduler := &hostScheduler{
+		perHostLimit: request.PerHostConcurrency,
+		gate:         request.gate,
+		delay:        time.Duration(request.PerHostDelayMs) * time.Millisecond,
+		jitter:       time.Duration(request.PerHostJitterMs) * time.Millisecond,
+		queues:       make(map[string][]indexedEntry),
+		followUps:    make(map[string][]chan struct{}),
+		inflight:     make(map[string]int),
+		readyAt:      make(map[string]time.Time),
+		requests:     make(chan followUp),
+	}
+	if request.RequestsPerSecond > 0 {
+		scheduler.interval = time.Second / time.Duration(request.RequestsPerSecond)
+	}
+	return scheduler
+}
+
+func (s *hostScheduler) enqueue(job indexedEntry) {
+	if _, ok := s.queues[job.Host]; !ok {
+		s.hosts = append(s.hosts, job.Host)
+	}
+	s.queues[job.Host] = append(s.queues[job.Host], job)
+}
+
+// wait holds a follow-up request to rawURL until the rate and delay limits
+// allow it. The worker asking already holds the concurrency slot of its job,
+// so follow-ups do not wait for one. A nil scheduler lets every request
+// through.
+func (s *hostScheduler) wait(ctx context.Context, rawURL string) error {
+	if s == nil {
+		return nil
+	}
+
+	ready := make(chan struct{})
+	select {
+	case s.requests <- followUp{host: hostKey(rawURL), ready: ready}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run queues the jobs arriving on incoming and sends them on jobs, honouring
+// the limits. Every job carries a slot of the gate, which the worker releases
+// once it is done. run closes jobs once incoming is closed and every job has
+// finished, or when ctx is cancelled. Workers must report the host of every
+// finished job on done, and done must be drained after run returns.
+func (s *hostScheduler) run(ctx context.Context, incoming <-chan indexedEntry, jobs chan<- indexedEntry, done <-chan string) {
+	defer close(jobs)
+
+	// slot receives the gate's answer while a job waits for it; held means
+	// the scheduler has a slot to send with the next job.
+	var slot chan error
+	held := false
+	defer func() {
+		if held {
+			s.gate.release()
+		}
+		if slot != nil {
+			go func(slot <-chan error) {
+				if <-slot == nil {
+					s.gate.release()
+				}
+			}(slot)
+		}
+	}()
+
+	pending, active := 0, 0
+	limit := s.pendingLimit()
+	accept := func(job indexedEntry, ok bool) {
+		if !ok {
+			incoming = nil
+			return
+		}
+		s.enqueue(job)
+		pending++
+	}
+
+	for incoming != nil || pending > 0 || active > 0 {
+		// Queue everything that has already arrived, so the round-robin
+		// sees every known host.
+	drain:
+		for incoming != nil && pending < limit {
+			select {
+			case job, ok := <-incoming:
+				accept(job, ok)
+			default:
+				break drain
+			}
+		}
+
+		var (
+			send  chan<- indexedEntry
+			next  indexedEntry
+			timer <-chan time.Time
+			feed  = incoming
+		)
+		if pending >= limit {
+			feed = nil
+		}
+		// Follow-ups go first: the workers waiting for them hold jobs that
+		// are already running.
+		now := time.Now()
+		host, wait, ok := s.pickFollowUp(now)
+		if ok {
+			s.granted(host, now)
+			continue
+		}
+		if pending > 0 {
+			host, jobWait, ok := s.pick(now)
+			switch {
+			case ok && (held || s.gate == nil):
+				send, next = jobs, s.queues[host][0]
+			case ok:
+				// Pick again once the gate answers: a paused job or a full
+				// budget may keep it waiting for a while.
+				if slot == nil && (s.workers == 0 || active < s.workers) {
+					slot = make(chan error, 1)
+					go func(slot chan<- error) {
+						slot <- s.gate.acquire(ctx)
+					}(slot)
+				}
+			case jobWait > 0 && (wait == 0 || jobWait < wait):
+				wait = jobWait
+			}
+		}
+		if wait > 0 {
+			timer = time.After(wait)
+		}
+
+		select {
+		case send <- next:
+			s.dispatched(next.Host, time.Now())
+			pending--
+			active++
+			held = false
+		case err := <-slot:
+			slot = nil
+			if err != nil {
+				return
+			}
+			held = true
+		case finished := <-done:
+			s.inflight[finished]--
+			active--
+		case request := <-s.requests:
+			s.followUps[request.host] = append(s.followUps[request.host], request.ready)
+		case job, ok := <-feed:
+			accept(job, ok)
+		case <-timer:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pendingLimit is how many jobs run queues before it stops reading its input:
+// enough to keep every worker busy and the round-robin fed.
+func (s *hostScheduler) pendingLimit() int {
+	workers := s.workers
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	return pendingPerWorker * workers
+}
+
+// pick returns the next host, in round-robin order, that has queued jobs and
+// may be sent a request now. Otherwise it returns how long to wait until the
+// earliest host becomes ready; zero means wait for an in-flight request.
+func (s *hostScheduler) pick(now time.Time) (string, time.Duration, bool) {
+	var wait time.Duration
+	for offset := 0; offset < len(s.hosts); offset++ {
+		index := (s.next + offset) % len(s.hosts)
+		host := s.hosts[index]
+		if len(s.queues[host]) == 0 {
+			continue
+		}
+		if s.perHostLimit > 0 && s.inflight[host] >= s.perHostLimit {
+			continue
+		}
+
+		if until := s.readyIn(host, now); until > 0 {
+			if wait == 0 || until < wait {
+				wait = until
+			}
+			continue
+		}
+		return host, 0, true
+	}
+	return "", wait, false
+}
+
+// pickFollowUp is pick for the hosts with waiting follow-ups, which need no
+// concurrency slot.
+func (s *hostScheduler) pickFollowUp(now time.Time) (string, time.Duration, bool) {
+	var wait time.Duration
+	for host := range s.followUps {
+		if until := s.readyIn(host, now); until > 0 {
+			if wait == 0 || until < wait {
+				wait = until
+			}
+			continue
+		}
+		return host, 0, true
+	}
+	return "", wait, false
+}
+
+// readyIn is how long host has to wait for the rate and delay limits.
+func (s *hostScheduler) readyIn(host string, now time.Time) time.Duration {
+	readyAt := s.readyAt[host]
+	if s.globalAt.After(readyAt) {
+		readyAt = s.globalAt
+	}
+	if readyAt.After(now) {
+		return readyAt.Sub(now)
+	}
+	return 0
+}
+
+// dispatched records a request sent to host. The round-robin only moves
+// past a host once it was actually sent a request, not whenever it was picked.
+func (s *hostScheduler) dispatched(host string, now time.Time) {
+	s.queues[host] = s.queues[host][1:]
+	s.inflight[host]++
+	s.next = slices.Index(s.hosts, host) + 1
+	s.space(host, now)
+}
+
+// granted lets the oldest follow-up for host go.
+func (s *hostScheduler) granted(host string, now time.Time) {
+	close(s.followUps[host][0])
+	if s.followUps[host] = s.followUps[host][1:]; len(s.followUps[host]) == 0 {
+		delete(s.followUps, host)
+	}
+	s.space(host, now)
+}
+
+// space holds back the next request to host, and to any host, as the limits
+// require after one was sent at now.
+func (s *hostScheduler) space(host string, now time.Time) {
+	if s.interval > 0 {
+		s.globalAt = now.Add(s.interval)
+	}
+	if s.delay > 0 || s.jitter > 0 {
+		pause := s.delay
+		if s.jitter > 0 {
+			pause += time.Duration(rand.Int63n(int64(s.jitter) + 1))
+		}
+		s.readyAt[host] = now.Add(pause)
+	}
+}
+
+// hostKey groups URLs by host and port for scheduling.
+func hostKey(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Host)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunScanWorkersLimitsPerHostConcurrency(t *testing.T) {
+	newServer := func() (*httptest.Server, func() int) {
+		var mu sync.Mutex
+		current, peak := 0, 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			current++
+			if current > peak {
+				peak = current
+			}
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			current--
+			mu.Unlock()
+		}))
+		return server, func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return peak
+		}
+	}
+
+	busy, busyPeak := newServer()
+	defer busy.Close()
+	quiet, quietPeak := newServer()
+	defer quiet.Close()
+
+	entries := make([]inputEntry, 0)
+	for _, path := range []string{"/a", "/b", "/c", "/d", "/e", "/f"} {
+		entries = append(entries, urlEntry(busy.URL+path))
+	}
+	entries = append(entries, urlEntry(quiet.URL+"/a"), urlEntry(quiet.URL+"/b"))
+
+	rows, err := runScanWorkers(context.Background(), entries, ScanRequest{
+		Concurrency:        8,
+		TimeoutSeconds:     5,
+		PerHostConcurrency: 2,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	if len(rows) != len(entries) {
+		t.Fatalf("expected %d rows, got %d", len(entries), len(rows))
+	}
+	if peak := busyPeak(); peak > 2 {
+		t.Fatalf("expected at most 2 concurrent requests to one host, got %d", peak)
+	}
+	if peak := quietPeak(); peak > 2 {
+		t.Fatalf("expected at most 2 concurrent requests to one host, got %d", peak)
+	}
+}
+
+func TestHostSchedulerSpacesRequests(t *testing.T) {
+	scheduler := newHostScheduler(ScanRequest{RequestsPerSecond: 10, PerHostDelayMs: 250})
+	scheduler.enqueue(indexedEntry{Index: 0, Host: "a"})
+	scheduler.enqueue(indexedEntry{Index: 1, Host: "a"})
+	scheduler.enqueue(indexedEntry{Index: 2, Host: "b"})
+
+	now := time.Now()
+	host, _, ok := scheduler.pick(now)
+	if !ok || host != "a" {
+		t.Fatalf("expected host a first, got %q ok=%v", host, ok)
+	}
+	scheduler.dispatched(host, now)
+
+	// The global limit of 10 rps blocks host b for 100ms.
+	if _, wait, ok := scheduler.pick(now); ok || wait != 100*time.Millisecond {
+		t.Fatalf("expected to wait 100ms for the global limit, got wait=%v ok=%v", wait, ok)
+	}
+
+	later := now.Add(100 * time.Millisecond)
+	host, _, ok = scheduler.pick(later)
+	if !ok || host != "b" {
+		t.Fatalf("expected host b once the global limit allows, got %q ok=%v", host, ok)
+	}
+	scheduler.dispatched(host, later)
+
+	// Host a still has to honour its 250ms per-host delay.
+	if _, wait, ok := scheduler.pick(later.Add(100 * time.Millisecond)); ok || wait != 50*time.Millisecond {
+		t.Fatalf("expected to wait 50ms for host a, got wait=%v ok=%v", wait, ok)
+	}
+}
+
+func TestHostSchedulerKeepsItsTurnUntilDispatched(t *testing.T) {
+	scheduler := newHostScheduler(ScanRequest{})
+	scheduler.enqueue(indexedEntry{Index: 0, Host: "a"})
+	scheduler.enqueue(indexedEntry{Index: 1, Host: "b"})
+	scheduler.enqueue(indexedEntry{Index: 2, Host: "a"})
+
+	now := time.Now()
+	host, _, _ := scheduler.pick(now)
+	scheduler.dispatched(host, now)
+
+	// Picking again without dispatching, e.g. while every worker is busy,
+	// must not skip host b.
+	for i := 0; i < 3; i++ {
+		if host, _, ok := scheduler.pick(now); !ok || host != "b" {
+			t.Fatalf("expected host b to keep its turn, got %q ok=%v", host, ok)
+		}
+	}
+}
+
+func TestHostSchedulerStopsReadingWhileItsQueueIsFull(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var produced atomic.Int64
+	endless := func(yield func(inputEntry) bool) {
+		for i := 0; ; i++ {
+			produced.Add(1)
+			if !yield(urlEntry(fmt.Sprintf("http://a/%d", i))) {
+				return
+			}
+		}
+	}
+
+	scheduler := newHostScheduler(ScanRequest{})
+	scheduler.workers = 2
+	jobs := make(chan indexedEntry)
+	go scheduler.run(ctx, feedEntries(ctx, endless), jobs, make(chan string))
+
+	// Both workers take a job and never finish it.
+	<-jobs
+	<-jobs
+	time.Sleep(100 * time.Millisecond)
+	if got, limit := produced.Load(), int64(scheduler.pendingLimit()); got > limit+4 {
+		t.Fatalf("expected at most %d queued entries to be generated, got %d", limit, got)
+	}
+}
@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListenAddress = "127.0.0.1:8765"
+	// defaultServeOutputDir holds the reports when serve is given no
+	// --output, so they never land next to the server's own files.
+	defaultServeOutputDir = "handlerdirsearch-reports"
+	apiTokenEnv           = "HANDLERDIRSEARCH_TOKEN"
+
+	maxSubmissionSize = 64 << 20
+	defaultRowsLimit  = 500
+	shutdownTimeout   = 10 * time.Second
+)
+
+// scanSubmission is the body of POST /api/jobs: a ScanRequest, optionally
+// with the input report itself instead of a path on the server.
+type scanSubmission struct {
+	ScanRequest
+	Input string `json:"input"`
+}
+
+// apiServer exposes a jobStore over HTTP. Every request needs the bearer
+// token. Reports always land under outputDir, and jobs may only read files on
+// the server when allowServerPaths is set.
+type apiServer struct {
+	token            string
+	outputDir        string
+	allowServerPaths bool
+	jobs             *jobStore
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/jobs", s.submitJob)
+	mux.HandleFunc("GET /api/jobs", s.listJobs)
+	mux.HandleFunc("GET /api/jobs/{id}", s.getJob)
+	mux.HandleFunc("GET /api/jobs/{id}/rows", s.jobRows)
+	mux.HandleFunc("GET /api/jobs/{id}/report", s.jobReport)
+	mux.HandleFunc("POST /api/jobs/{id}/cancel", s.cancelJob)
+	return s.authenticate(mux)
+}
+
+func (s *apiServer) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="handlerdirsearch"`)
+			writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *apiServer) submitJob(w http.ResponseWriter, r *http.Request) {
+	var submission scanSubmission
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSubmissionSize)).Decode(&submission); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+		return
+	}
+
+	request := normalizeScanRequest(submission.ScanRequest)
+	if err := s.checkSubmission(&request); err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var (
+		plan *scanPlan
+		err  error
+	)
+	if submission.Input != "" {
+		request.InputFilePath = stdinInputPath
+		plan, err = prepareStreamScan(request, strings.NewReader(submission.Input))
+	} else {
+		plan, err = prepareScan(request)
+	}
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	job := s.jobs.submit(plan)
+	w.Header().Set("Location", "/api/jobs/"+job.id)
+	writeJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+// checkSubmission keeps a remote request from touching the server's files:
+// it may not delete its input, its report directory is confined to
+// outputDir, and paths on the server are refused unless allowServerPaths is
+// set.
+func (s *apiServer) checkSubmission(request *ScanRequest) error {
+	if request.DeleteSourceAfterRun {
+		return errors.New("deleteSourceAfterRun is not allowed over the API")
+	}
+
+	dir := strings.TrimSpace(request.OutputDir)
+	if dir != "" && !filepath.IsLocal(dir) {
+		return fmt.Errorf("outputDir %q must be a relative path inside the server's output directory", request.OutputDir)
+	}
+	if fields := serverPathFields(*request); len(fields) > 0 && !s.allowServerPaths {
+		return fmt.Errorf("%s refer to files on the server; send the report as \"input\" or start serve with --allow-server-paths", strings.Join(fields, ", "))
+	}
+
+	request.OutputDir = filepath.Join(s.outputDir, dir)
+	if dir == "" {
+		return nil
+	}
+	return os.MkdirAll(request.OutputDir, 0o755)
+}
+
+// serverPathFields names the fields of request that make the scan read
+// files, or the saved auth profiles, on the machine running it.
+func serverPathFields(request ScanRequest) []string {
+	fields := make([]string, 0)
+	for _, field := range []struct {
+		name string
+		set  bool
+	}{
+		{"inputFilePath", strings.TrimSpace(request.InputFilePath) != ""},
+		{"inputFilePaths", len(request.InputFilePaths) > 0},
+		{"wordlistPath", strings.TrimSpace(request.WordlistPath) != ""},
+		{"fingerprintRulePaths", strings.TrimSpace(request.FingerprintRulePaths) != ""},
+		{"caBundlePath", strings.TrimSpace(request.CABundlePath) != ""},
+		{"clientCertPath", strings.TrimSpace(request.ClientCertPath) != ""},
+		{"clientKeyPath", strings.TrimSpace(request.ClientKeyPath) != ""},
+		{"authProfile", strings.TrimSpace(request.AuthProfile) != ""},
+	} {
+		if field.set {
+			fields = append(fields, field.name)
+		}
+	}
+	return fields
+}
+
+func (s *apiServer) listJobs(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.jobs.list())
+}
+
+func (s *apiServer) getJob(w http.ResponseWriter, r *http.Request) {
+	if job, ok := s.lookupJob(w, r); ok {
+		writeJSON(w, http.StatusOK, job.snapshot())
+	}
+}
+
+func (s *apiServer) jobRows(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+	limit, err := queryInt(r, "limit", defaultRowsLimit)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	rows, total := job.rowsPage(offset, limit)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": job.snapshot().Status,
+		"offset": offset,
+		"total":  total,
+		"rows":   rows,
+	})
+}
+
+// jobReport renders the job's own report section, so it does not include
+// earlier runs appended to the same report file.
+func (s *apiServer) jobReport(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = reportFormatMarkdown
+	}
+	contentType := map[string]string{
+		reportFormatMarkdown: "text/markdown; charset=utf-8",
+		reportFormatJSON:     "application/json",
+		reportFormatCSV:      "text/csv; charset=utf-8",
+	}[format]
+	if contentType == "" {
+		writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q, want md, json or csv", format))
+		return
+	}
+
+	response, ok := job.result()
+	if !ok {
+		if snapshot := job.snapshot(); snapshot.CancelledBeforeStart {
+			writeAPIError(w, http.StatusConflict, errors.New("job was cancelled before start"))
+		} else {
+			writeAPIError(w, http.StatusConflict, fmt.Errorf("job is %s", snapshot.Status))
+		}
+		return
+	}
+
+	request := job.plan.request
+	fileName := exportFileName(request.InputFilePath, format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	_ = writeReport(w, request, response, format)
+}
+
+func (s *apiServer) cancelJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.lookupJob(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.jobs.cancel(job); err != nil {
+		writeAPIError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, job.snapshot())
+}
+
+func (s *apiServer) lookupJob(w http.ResponseWriter, r *http.Request) (*scanJob, bool) {
+	job, ok := s.jobs.get(r.PathValue("id"))
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, errors.New("job not found"))
+	}
+	return job, ok
+}
+
+func queryInt(r *http.Request, name string, value int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return value, nil
+	}
+	parsed, err := strconv.Atoi(raw)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, raw)
+	}
+	return parsed, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// runServeCommand serves the API until ctx is cancelled, then cancels the
+// running jobs and waits for their partial reports.
+func runServeCommand(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	var (
+		listen, token, outputDir string
+		allowServerPaths         bool
+		maxJobs, budget          int
+	)
+	stringFlag(flags, &listen, defaultListenAddress, "l", "listen")
+	stringFlag(flags, &token, os.Getenv(apiTokenEnv), "token")
+	stringFlag(flags, &outputDir, defaultServeOutputDir, "o", "output")
+	flags.BoolVar(&allowServerPaths, "allow-server-paths", false, "")
+	intFlag(flags, &maxJobs, defaultMaxRunningJobs, "max-jobs")
+	intFlag(flags, &budget, defaultScanBudget, "budget")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			io.WriteString(stdout, cliUsage)
+			return exitOK
+		}
+		fmt.Fprintf(stderr, "%v\n\n%s", err, cliUsage)
+		return exitUsage
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintf(stderr, "unexpected argument %q\n\n%s", flags.Arg(0), cliUsage)
+		return exitUsage
+	}
+	if strings.TrimSpace(outputDir) == "" {
+		fmt.Fprintf(stderr, "--output must not be empty\n\n%s", cliUsage)
+		return exitUsage
+	}
+	if maxJobs <= 0 || budget <= 0 {
+		fmt.Fprintf(stderr, "--max-jobs and --budget must be greater than 0\n\n%s", cliUsage)
+		return exitUsage
+	}
+
+	if token == "" {
+		token = randomToken() + randomToken()
+		fmt.Fprintln(stderr, "generated API token:", token)
+	}
+
+	outputDir, err := filepath.Abs(outputDir)
+	if err == nil {
+		err = os.MkdirAll(outputDir, 0o755)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	}
+
+	listener, err := net.Listen("tcp", listen)
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	}
+
+	jobs := newJobStore(ctx)
+	jobs.setLimits(maxJobs, budget)
+	api := &apiServer{token: token, outputDir: outputDir, allowServerPaths: allowServerPaths, jobs: jobs}
+	server := &http.Server{Handler: api.routes(), ReadHeaderTimeout: 10 * time.Second}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(listener)
+	}()
+	fmt.Fprintf(stderr, "serving the scan API on http://%s\n", listener.Addr())
+
+	select {
+	case err := <-served:
+		fmt.Fprintln(stderr, "error:", err)
+		return exitScanFailed
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(stderr, "shutting down, cancelling running jobs")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	jobs.wait()
+	return exitOK
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAPIToken = "secret"
+
+func newTestAPI(t *testing.T) (*httptest.Server, *jobStore) {
+	t.Helper()
+	return startTestAPI(t, &apiServer{outputDir: t.TempDir()})
+}
+
+// startTestAPI serves server, filling in the token and a job store.
+func startTestAPI(t *testing.T, server *apiServer) (*httptest.Server, *jobStore) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	jobs := newJobStore(ctx)
+	server.token, server.jobs = testAPIToken, jobs
+	api := httptest.NewServer(server.routes())
+	t.Cleanup(func() {
+		api.Close()
+		cancel()
+		jobs.wait()
+	})
+	return api, jobs
+}
+
+func apiCall(t *testing.T, api *httptest.Server, method, path string, body any, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, api.URL+path, reader)
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+testAPIToken)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer response.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(response.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, path, err)
+		}
+	}
+	return response.StatusCode
+}
+
+func waitForJob(t *testing.T, api *httptest.Server, id string, statuses ...string) ScanJob {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		var job ScanJob
+		apiCall(t, api, http.MethodGet, "/api/jobs/"+id, nil, &job)
+		for _, status := range statuses {
+			if job.Status == status {
+				return job
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("job %s stuck in %+v", id, job)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAPIRequiresToken(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	for _, header := range []string{"", "Bearer wrong", testAPIToken} {
+		request, _ := http.NewRequest(http.MethodGet, api.URL+"/api/jobs", nil)
+		if header != "" {
+			request.Header.Set("Authorization", header)
+		}
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			t.Fatalf("list jobs: %v", err)
+		}
+		response.Body.Close()
+		if response.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("expected 401 for %q, got %d", header, response.StatusCode)
+		}
+	}
+
+	var jobs []ScanJob
+	if status := apiCall(t, api, http.MethodGet, "/api/jobs", nil, &jobs); status != http.StatusOK || len(jobs) != 0 {
+		t.Fatalf("expected an empty job list, got %d %+v", status, jobs)
+	}
+}
+
+func TestAPIRunsSubmittedScans(t *testing.T) {
+	target := newCLITestServer(t)
+	api, _ := newTestAPI(t)
+
+	var submitted ScanJob
+	status := apiCall(t, api, http.MethodPost, "/api/jobs", map[string]any{
+		"input":          "200 " + target.URL + "/a\n403 " + target.URL + "/b\n404 " + target.URL + "/c\n",
+		"concurrency":    2,
+		"disableFavicon": true,
+	}, &submitted)
+	if status != http.StatusAccepted || submitted.ID == "" || submitted.InputFile != stdinInputPath {
+		t.Fatalf("unexpected submit response %d %+v", status, submitted)
+	}
+
+	job := waitForJob(t, api, submitted.ID, jobFinished)
+	if job.Progress.Done != 2 || job.FinishedAt == "" || job.ReportPath == "" {
+		t.Fatalf("unexpected finished job %+v", job)
+	}
+	if _, err := os.Stat(job.ReportPath); err != nil {
+		t.Fatalf("expected the report on disk: %v", err)
+	}
+
+	var page struct {
+		Total int       `json:"total"`
+		Rows  []ScanRow `json:"rows"`
+	}
+	apiCall(t, api, http.MethodGet, "/api/jobs/"+job.ID+"/rows?offset=1&limit=5", nil, &page)
+	if page.Total != 2 || len(page.Rows) != 1 || page.Rows[0].URL != target.URL+"/b" {
+		t.Fatalf("unexpected rows page %+v", page)
+	}
+
+	request, _ := http.NewRequest(http.MethodGet, api.URL+"/api/jobs/"+job.ID+"/report?format=csv", nil)
+	request.Header.Set("Authorization", "Bearer "+testAPIToken)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("download report: %v", err)
+	}
+	defer response.Body.Close()
+	records, err := csv.NewReader(response.Body).ReadAll()
+	if err != nil || len(records) != 3 {
+		t.Fatalf("expected a CSV report with two rows, got %v %v", err, records)
+	}
+	if disposition := response.Header.Get("Content-Disposition"); !strings.Contains(disposition, "stdin_report.csv") {
+		t.Fatalf("unexpected Content-Disposition %q", disposition)
+	}
+
+	var jobs []ScanJob
+	apiCall(t, api, http.MethodGet, "/api/jobs", nil, &jobs)
+	if len(jobs) != 1 || jobs[0].ID != job.ID {
+		t.Fatalf("expected the job in the list, got %+v", jobs)
+	}
+}
+
+func TestAPIScansInputFilesOnTheServer(t *testing.T) {
+	target := newCLITestServer(t)
+	outputDir := t.TempDir()
+	api, _ := startTestAPI(t, &apiServer{outputDir: outputDir, allowServerPaths: true})
+	inputPath := writeCLIInput(t, "200 "+target.URL+"/a")
+
+	var submitted ScanJob
+	apiCall(t, api, http.MethodPost, "/api/jobs", ScanRequest{InputFilePath: inputPath, OutputDir: "nightly", DisableFavicon: true}, &submitted)
+	job := waitForJob(t, api, submitted.ID, jobFinished)
+	if job.ReportPath != filepath.Join(outputDir, "nightly", "hits_report.md") {
+		t.Fatalf("expected the requested directory under the server's output directory, got %+v", job)
+	}
+	if _, err := os.Stat(inputPath); err != nil {
+		t.Fatalf("expected the input file to be kept: %v", err)
+	}
+}
+
+func TestAPIKeepsRemoteJobsAwayFromServerFiles(t *testing.T) {
+	api, _ := newTestAPI(t)
+	inputPath := writeCLIInput(t, "200 http://127.0.0.1:1/a")
+	input := "200 http://127.0.0.1:1/a\n"
+
+	cases := []struct {
+		name string
+		body map[string]any
+		want string
+	}{
+		{"delete source", map[string]any{"input": input, "deleteSourceAfterRun": true}, "deleteSourceAfterRun"},
+		{"absolute output", map[string]any{"input": input, "outputDir": t.TempDir()}, "outputDir"},
+		{"escaping output", map[string]any{"input": input, "outputDir": "../elsewhere"}, "outputDir"},
+		{"input file", map[string]any{"inputFilePath": inputPath}, "inputFilePath"},
+		{"input files", map[string]any{"inputFilePaths": []string{filepath.Dir(inputPath)}}, "inputFilePaths"},
+		{"wordlist", map[string]any{"input": input, "mode": "discover", "wordlistPath": "/etc/passwd"}, "wordlistPath"},
+		{"fingerprint rules", map[string]any{"input": input, "fingerprintRulePaths": "/etc"}, "fingerprintRulePaths"},
+		{"CA bundle", map[string]any{"input": input, "caBundlePath": "/etc/ssl/certs"}, "caBundlePath"},
+		{"client certificate", map[string]any{"input": input, "clientCertPath": "/root/cert.pem", "clientKeyPath": "/root/key.pem"}, "clientCertPath, clientKeyPath"},
+		{"auth profile", map[string]any{"input": input, "authProfile": "prod"}, "authProfile"},
+	}
+	for _, tc := range cases {
+		var errorBody map[string]string
+		if status := apiCall(t, api, http.MethodPost, "/api/jobs", tc.body, &errorBody); status != http.StatusBadRequest || !strings.Contains(errorBody["error"], tc.want) {
+			t.Fatalf("%s: expected a 400 naming %s, got %d %v", tc.name, tc.want, status, errorBody)
+		}
+	}
+	if _, err := os.Stat(inputPath); err != nil {
+		t.Fatalf("expected the input file to be untouched: %v", err)
+	}
+}
+
+func TestAPIRejectsInvalidRequests(t *testing.T) {
+	api, _ := newTestAPI(t)
+
+	cases := []struct {
+		method, path string
+		body         any
+		status       int
+	}{
+		{http.MethodPost, "/api/jobs", ScanRequest{}, http.StatusBadRequest},
+		{http.MethodPost, "/api/jobs", ScanRequest{InputFilePath: filepath.Join(t.TempDir(), "missing.txt")}, http.StatusBadRequest},
+		{http.MethodPost, "/api/jobs", map[string]any{"input": "200 http://127.0.0.1:1/", "proxyUrl": "ftp://proxy"}, http.StatusBadRequest},
+		{http.MethodGet, "/api/jobs/unknown", nil, http.StatusNotFound},
+		{http.MethodPost, "/api/jobs/unknown/cancel", nil, http.StatusNotFound},
+		{http.MethodDelete, "/api/jobs", nil, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		if status := apiCall(t, api, tc.method, tc.path, tc.body, nil); status != tc.status {
+			t.Fatalf("%s %s: expected %d, got %d", tc.method, tc.path, tc.status, status)
+		}
+	}
+}
+
+func TestAPICancelsRunningJobs(t *testing.T) {
+	started := make(chan struct{}, 1)
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-r.Context().Done()
+			return
+		}
+		_, _ = w.Write([]byte("<title>fast</title>"))
+	}))
+	defer target.Close()
+	api, _ := newTestAPI(t)
+
+	var submitted ScanJob
+	apiCall(t, api, http.MethodPost, "/api/jobs", map[string]any{
+		"input":          "200 " + target.URL + "/fast\n200 " + target.URL + "/slow\n",
+		"concurrency":    1,
+		"timeoutSeconds": 30,
+		"disableFavicon": true,
+	}, &submitted)
+	<-started
+
+	var errorBody map[string]string
+	if status := apiCall(t, api, http.MethodGet, "/api/jobs/"+submitted.ID+"/report", nil, &errorBody); status != http.StatusConflict {
+		t.Fatalf("expected no report while running, got %d", status)
+	}
+	if status := apiCall(t, api, http.MethodPost, "/api/jobs/"+submitted.ID+"/cancel", nil, nil); status != http.StatusAccepted {
+		t.Fatalf("expected the cancel to be accepted, got %d", status)
+	}
+
+	job := waitForJob(t, api, submitted.ID, jobCancelled)
+	if job.Progress.Done != 1 {
+		t.Fatalf("expected the finished row to be kept, got %+v", job)
+	}
+	if status := apiCall(t, api, http.MethodPost, "/api/jobs/"+submitted.ID+"/cancel", nil, nil); status != http.StatusConflict {
+		t.Fatalf("expected a second cancel to conflict, got %d", status)
+	}
+}
+
+func TestAPIReportsJobsCancelledBeforeStart(t *testing.T) {
+	target, release, _ := newBlockingServer(t)
+	defer close(release)
+	api, jobs := newTestAPI(t)
+	jobs.setLimits(1, 0)
+
+	submit := func(path string) ScanJob {
+		var job ScanJob
+		apiCall(t, api, http.MethodPost, "/api/jobs", map[string]any{
+			"input":          "200 " + target.URL + path + "\n",
+			"concurrency":    1,
+			"disableFavicon": true,
+		}, &job)
+		return job
+	}
+	submit("/slow")
+	queued := submit("/fast")
+
+	var job ScanJob
+	if status := apiCall(t, api, http.MethodPost, "/api/jobs/"+queued.ID+"/cancel", nil, &job); status != http.StatusAccepted {
+		t.Fatalf("expected the cancel to be accepted, got %d", status)
+	}
+	if job.Status != jobCancelled || !job.CancelledBeforeStart || job.StartedAt != "" || job.ReportPath != "" {
+		t.Fatalf("expected the job to be cancelled before start, got %+v", job)
+	}
+
+	var errorBody map[string]string
+	if status := apiCall(t, api, http.MethodGet, "/api/jobs/"+queued.ID+"/report", nil, &errorBody); status != http.StatusConflict || !strings.Contains(errorBody["error"], "cancelled before start") {
+		t.Fatalf("expected no report for a job cancelled before start, got %d %v", status, errorBody)
+	}
+}
+
+func TestServeCommandDefaultsItsOutputDirAndJobLimits(t *testing.T) {
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("chdir: %v", err)
+	}
+	defer os.Chdir(previous)
+	workDir, _ := os.Getwd()
+
+	target, release, _ := newBlockingServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	logs, logWriter := io.Pipe()
+	exited := make(chan int, 1)
+	go func() {
+		exited <- runServeCommand(ctx, []string{"-l", "127.0.0.1:0", "--token", testAPIToken}, io.Discard, logWriter)
+		logWriter.Close()
+	}()
+
+	lines := bufio.NewScanner(logs)
+	api := &httptest.Server{}
+	for api.URL == "" && lines.Scan() {
+		if address, ok := strings.CutPrefix(lines.Text(), "serving the scan API on "); ok {
+			api.URL = address
+		}
+	}
+	go io.Copy(io.Discard, logs)
+	if api.URL == "" {
+		t.Fatal("serve did not start")
+	}
+
+	ids := make([]string, 3)
+	for i := range ids {
+		var job ScanJob
+		apiCall(t, api, http.MethodPost, "/api/jobs", map[string]any{"input": "200 " + target.URL + "/slow\n", "disableFavicon": true}, &job)
+		ids[i] = job.ID
+	}
+	if job := waitForJob(t, api, ids[2], jobQueued, jobRunning); job.Status != jobQueued {
+		t.Fatalf("expected the third job to wait for the default limit of %d jobs, got %+v", defaultMaxRunningJobs, job)
+	}
+
+	close(release)
+	job := waitForJob(t, api, ids[0], jobFinished)
+	if want := filepath.Join(workDir, defaultServeOutputDir, "stdin_report.md"); job.ReportPath != want {
+		t.Fatalf("expected the report in %s, got %+v", want, job)
+	}
+	waitForJob(t, api, ids[2], jobFinished)
+
+	cancel()
+	if code := <-exited; code != exitOK {
+		t.Fatalf("expected serve to exit cleanly, got %d", code)
+	}
+}
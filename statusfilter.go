@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultIncludeStatus = "200,301,403"
+
+// statusRange is an inclusive range of HTTP status codes. label keeps the
+// spelling the user typed so reports can echo it back.
+type statusRange struct {
+	min   int
+	max   int
+	label string
+}
+
+// statusFilter decides which status codes are kept. A code is kept when it is
+// in any include range and in no exclude range.
+type statusFilter struct {
+	include []statusRange
+	exclude []statusRange
+}
+
+func defaultStatusFilter() statusFilter {
+	filter, _ := parseStatusFilter(defaultIncludeStatus, "")
+	return filter
+}
+
+// parseStatusFilter parses comma separated include and exclude lists. Each
+// entry is a single code ("302"), a class ("2xx") or a range ("500-599").
+// An empty include list keeps every status code.
+func parseStatusFilter(include, exclude string) (statusFilter, error) {
+	includeRanges, err := parseStatusRanges(include)
+	if err != nil {
+		return statusFilter{}, err
+	}
+	if len(includeRanges) == 0 {
+		includeRanges = []statusRange{{min: 100, max: 599, label: "all"}}
+	}
+
+	excludeRanges, err := parseStatusRanges(exclude)
+	if err != nil {
+		return statusFilter{}, err
+	}
+
+	return statusFilter{include: includeRanges, exclude: excludeRanges}, nil
+}
+
+func parseStatusRanges(spec string) ([]statusRange, error) {
+	fields := strings.FieldsFunc(spec, func(r rune) bool {
+		return r == ',' || r == ';' || r == ' ' || r == '\t' || r == '\n'
+	})
+
+	ranges := make([]statusRange, 0, len(fields))
+	for _, field := range fields {
+		statusRange, err := parseStatusRange(field)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, statusRange)
+	}
+
+	return ranges, nil
+}
+
+func parseStatusRange(field string) (statusRange, error) {
+	lower := strings.ToLower(strings.TrimSpace(field))
+
+	if len(lower) == 3 && strings.HasSuffix(lower, "xx") {
+		class, err := strconv.Atoi(lower[:1])
+		if err != nil || class < 1 || class > 5 {
+			return statusRange{}, fmt.Errorf("invalid status class %q", field)
+		}
+		return statusRange{min: class * 100, max: class*100 + 99, label: lower}, nil
+	}
+
+	if from, to, ok := strings.Cut(lower, "-"); ok {
+		minCode, err := parseStatusCode(from)
+		if err != nil {
+			return statusRange{}, err
+		}
+		maxCode, err := parseStatusCode(to)
+		if err != nil {
+			return statusRange{}, err
+		}
+		if minCode > maxCode {
+			return statusRange{}, fmt.Errorf("invalid status range %q", field)
+		}
+		return statusRange{min: minCode, max: maxCode, label: fmt.Sprintf("%d-%d", minCode, maxCode)}, nil
+	}
+
+	code, err := parseStatusCode(lower)
+	if err != nil {
+		return statusRange{}, err
+	}
+	return statusRange{min: code, max: code, label: strconv.Itoa(code)}, nil
+}
+
+func parseStatusCode(value string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || code < 100 || code > 599 {
+		return 0, fmt.Errorf("invalid status code %q", value)
+	}
+	return code, nil
+}
+
+func (f statusFilter) Match(code int) bool {
+	for _, excluded := range f.exclude {
+		if excluded.contains(code) {
+			return false
+		}
+	}
+
+	for _, included := range f.include {
+		if included.contains(code) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// String renders the filter for reports, e.g. "200/301/403" or
+// "2xx/401 excluding 204".
+func (f statusFilter) String() string {
+	description := joinStatusLabels(f.include)
+	if len(f.exclude) > 0 {
+		description += " excluding " + joinStatusLabels(f.exclude)
+	}
+	return description
+}
+
+func (r statusRange) contains(code int) bool {
+	return code >= r.min && code <= r.max
+}
+
+func joinStatusLabels(ranges []statusRange) string {
+	labels := make([]string, 0, len(ranges))
+	for _, statusRange := range ranges {
+		labels = append(labels, statusRange.label)
+	}
+	return strings.Join(labels, "/")
+}
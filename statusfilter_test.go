@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParseStatusFilterSupportsCodesClassesAndRanges(t *testing.T) {
+	filter, err := parseStatusFilter("2xx, 302;401 500-503", "204")
+	if err != nil {
+		t.Fatalf("parse filter: %v", err)
+	}
+
+	cases := map[int]bool{
+		200: true,
+		204: false,
+		299: true,
+		301: false,
+		302: true,
+		401: true,
+		403: false,
+		502: true,
+		504: false,
+	}
+	for code, expected := range cases {
+		if filter.Match(code) != expected {
+			t.Fatalf("expected Match(%d)=%v", code, expected)
+		}
+	}
+
+	if filter.String() != "2xx/302/401/500-503 excluding 204" {
+		t.Fatalf("unexpected description %q", filter.String())
+	}
+	if defaultStatusFilter().String() != "200/301/403" {
+		t.Fatalf("unexpected default description %q", defaultStatusFilter().String())
+	}
+
+	for _, invalid := range []string{"abc", "6xx", "99", "500-400"} {
+		if _, err := parseStatusFilter(invalid, ""); err == nil {
+			t.Fatalf("expected error for %q", invalid)
+		}
+	}
+}
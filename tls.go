@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// TLSInfo describes the certificate and connection of an HTTPS response.
+type TLSInfo struct {
+	Version          string   `json:"version"`
+	CipherSuite      string   `json:"cipherSuite"`
+	Subject          string   `json:"subject"`
+	Issuer           string   `json:"issuer"`
+	SANs             []string `json:"sans"`
+	NotBefore        string   `json:"notBefore"`
+	NotAfter         string   `json:"notAfter"`
+	KeyType          string   `json:"keyType"`
+	Fingerprint      string   `json:"fingerprint"`
+	Expired          bool     `json:"expired"`
+	SelfSigned       bool     `json:"selfSigned"`
+	HostnameMismatch bool     `json:"hostnameMismatch"`
+}
+
+// newTLSConfig builds the client TLS settings of a scan: optional insecure
+// mode, extra trusted CAs and a client certificate for mTLS targets.
+func newTLSConfig(request ScanRequest) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: request.InsecureSkipVerify}
+
+	if caPath := strings.TrimSpace(request.CABundlePath); caPath != "" {
+		pool, err := loadCABundle(caPath)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+
+	if certPath := strings.TrimSpace(request.ClientCertPath); certPath != "" {
+		cert, err := loadClientCertificate(certPath, strings.TrimSpace(request.ClientKeyPath), request.ClientCertPassword)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
+
+// loadCABundle adds the PEM certificates of path to the system roots, so
+// internal CAs are trusted without losing the public ones.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read CA bundle: %w", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificate found in CA bundle %s", path)
+	}
+	return pool, nil
+}
+
+// loadClientCertificate reads a PKCS#12 bundle (.p12/.pfx) or a PEM
+// certificate with its key, which may live in the same file.
+func loadClientCertificate(certPath, keyPath, password string) (tls.Certificate, error) {
+	switch strings.ToLower(filepath.Ext(certPath)) {
+	case ".p12", ".pfx":
+		data, err := os.ReadFile(certPath)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("read client certificate: %w", err)
+		}
+
+		key, leaf, chain, err := pkcs12.DecodeChain(data, password)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("decode PKCS#12 client certificate: %w", err)
+		}
+
+		cert := tls.Certificate{PrivateKey: key, Leaf: leaf, Certificate: [][]byte{leaf.Raw}}
+		for _, ca := range chain {
+			cert.Certificate = append(cert.Certificate, ca.Raw)
+		}
+		return cert, nil
+	default:
+		if keyPath == "" {
+			keyPath = certPath
+		}
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return tls.Certificate{}, fmt.Errorf("load client certificate: %w", err)
+		}
+		return cert, nil
+	}
+}
+
+// inspectTLS summarises the leaf certificate of a connection to host.
+func inspectTLS(state *tls.ConnectionState, host string, now time.Time) *TLSInfo {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil
+	}
+
+	info := inspectCertificate(state.PeerCertificates[0], host, now)
+	info.Version = tls.VersionName(state.Version)
+	info.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	return info
+}
+
+// inspectCertificateError recovers the certificate from a failed handshake,
+// so expired, self-signed and mismatched certificates are reported even
+// though the request itself failed verification.
+func inspectCertificateError(err error, now time.Time) *TLSInfo {
+	var verifyErr *tls.CertificateVerificationError
+	if !errors.As(err, &verifyErr) || len(verifyErr.UnverifiedCertificates) == 0 {
+		return nil
+	}
+
+	// The failing request may be a redirect hop, so take the host from the
+	// error rather than from the scanned URL.
+	host := ""
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if parsed, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			host = parsed.Hostname()
+		}
+	}
+	return inspectCertificate(verifyErr.UnverifiedCertificates[0], host, now)
+}
+
+func inspectCertificate(cert *x509.Certificate, host string, now time.Time) *TLSInfo {
+	sum := sha256.Sum256(cert.Raw)
+	info := &TLSInfo{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		SANs:        certificateNames(cert),
+		NotBefore:   cert.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:    cert.NotAfter.UTC().Format(time.RFC3339),
+		KeyType:     describePublicKey(cert.PublicKey),
+		Fingerprint: hex.EncodeToString(sum[:]),
+		Expired:     now.After(cert.NotAfter) || now.Before(cert.NotBefore),
+		SelfSigned:  isSelfSigned(cert),
+	}
+	if host != "" {
+		info.HostnameMismatch = cert.VerifyHostname(host) != nil
+	}
+	return info
+}
+
+// certificateNames returns the DNS and IP SANs, or the common name for old
+// certificates without SANs.
+func certificateNames(cert *x509.Certificate) []string {
+	names := append([]string(nil), cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	if len(names) == 0 && cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	return names
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	if !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignatureFrom(cert) == nil
+}
+
+func describePublicKey(key any) string {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return "unknown"
+	}
+}
+
+// collectSANPivots returns the SAN hostnames that were not part of the scan,
+// i.e. sibling hosts worth adding to the target list.
+func collectSANPivots(rows []ScanRow) []string {
+	scanned := make(map[string]struct{})
+	for _, row := range rows {
+		for _, rawURL := range []string{row.URL, row.FinalURL} {
+			if parsed, err := url.Parse(rawURL); err == nil && parsed.Hostname() != "" {
+				scanned[strings.ToLower(parsed.Hostname())] = struct{}{}
+			}
+		}
+	}
+
+	seen := make(map[string]struct{})
+	pivots := make([]string, 0)
+	for _, row := range rows {
+		if row.TLS == nil {
+			continue
+		}
+		for _, name := range row.TLS.SANs {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			if _, ok := scanned[name]; ok {
+				continue
+			}
+			if _, ok := seen[name]; ok {
+				continue
+			}
+			seen[name] = struct{}{}
+			pivots = append(pivots, name)
+		}
+	}
+
+	sort.Strings(pivots)
+	return pivots
+}
+
+func formatTLSFlags(info *TLSInfo) string {
+	flags := make([]string, 0, 3)
+	if info.Expired {
+		flags = append(flags, "expired")
+	}
+	if info.SelfSigned {
+		flags = append(flags, "self-signed")
+	}
+	if info.HostnameMismatch {
+		flags = append(flags, "hostname mismatch")
+	}
+	return strings.Join(flags, ", ")
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestInspectTLSDescribesConnection(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resp, err := server.Client().Get(server.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+
+	info := inspectTLS(resp.TLS, "127.0.0.1", time.Now())
+	if info == nil {
+		t.Fatal("expected TLS info")
+	}
+	if !strings.HasPrefix(info.Version, "TLS 1.") || info.CipherSuite == "" {
+		t.Fatalf("expected version and cipher, got %q %q", info.Version, info.CipherSuite)
+	}
+	if info.KeyType == "" || info.KeyType == "unknown" || len(info.Fingerprint) != 64 {
+		t.Fatalf("expected key type and fingerprint, got %q %q", info.KeyType, info.Fingerprint)
+	}
+	if !info.SelfSigned || info.Expired || info.HostnameMismatch {
+		t.Fatalf("expected a valid self-signed certificate for 127.0.0.1, got %+v", info)
+	}
+
+	mismatch := inspectTLS(resp.TLS, "intranet.test", time.Now())
+	if !mismatch.HostnameMismatch {
+		t.Fatal("expected hostname mismatch for intranet.test")
+	}
+	expired := inspectTLS(resp.TLS, "127.0.0.1", time.Now().AddDate(100, 0, 0))
+	if !expired.Expired || formatTLSFlags(expired) != "expired, self-signed" {
+		t.Fatalf("expected expired flag, got %q", formatTLSFlags(expired))
+	}
+}
+
+func TestRunScanWorkersReportsCertificateOfFailedHandshake(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL)}, ScanRequest{Concurrency: 1, TimeoutSeconds: 5}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	row := rows[0]
+	if row.Error == "" || row.TLS == nil {
+		t.Fatalf("expected verification failure with certificate details, got %+v", row)
+	}
+	if !row.TLS.SelfSigned || row.TLS.HostnameMismatch {
+		t.Fatalf("expected self-signed flag only, got %+v", row.TLS)
+	}
+
+	pivots := collectSANPivots(rows)
+	if strings.Join(pivots, ",") != "*.example.com,::1,example.com" {
+		t.Fatalf("unexpected SAN pivots %v", pivots)
+	}
+}
+
+func TestAppendMarkdownReportListsCertificatesAndPivots(t *testing.T) {
+	reportPath := t.TempDir() + "/scan_report.md"
+	info := &TLSInfo{
+		Version:     "TLS 1.3",
+		CipherSuite: "TLS_AES_128_GCM_SHA256",
+		Subject:     "CN=www.example.com",
+		Issuer:      "CN=Example CA",
+		SANs:        []string{"www.example.com", "vpn.example.com"},
+		NotBefore:   "2024-01-01T00:00:00Z",
+		NotAfter:    "2025-01-01T00:00:00Z",
+		KeyType:     "ECDSA P-256",
+		Fingerprint: "ab",
+		Expired:     true,
+	}
+	rows := []ScanRow{
+		{URL: "https://www.example.com/a", StatusCode: 200, TLS: info},
+		{URL: "https://www.example.com/b", StatusCode: 200, TLS: info},
+	}
+	response := ScanResponse{TotalURLs: 2, Rows: rows, SANPivots: collectSANPivots(rows)}
+
+	if err := appendMarkdownReport(reportPath, "input.txt", response); err != nil {
+		t.Fatalf("appendMarkdownReport returned error: %v", err)
+	}
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+
+	content := string(data)
+	for _, want := range []string{
+		"### TLS Certificates",
+		"| www.example.com | CN=www.example.com | CN=Example CA | www.example.com, vpn.example.com | 2024-01-01 ~ 2025-01-01 | ECDSA P-256 | TLS 1.3 TLS_AES_128_GCM_SHA256 | expired |",
+		"### SAN Pivot Candidates\n\n- `vpn.example.com`\n\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected report to contain %q:\n%s", want, content)
+		}
+	}
+	if strings.Count(content, "| www.example.com | CN=") != 1 {
+		t.Fatalf("expected the certificate to be listed once:\n%s", content)
+	}
+}
+
+func TestRunScanWorkersPresentsClientCertificates(t *testing.T) {
+	dir := t.TempDir()
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Internal CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	caCert, _ := x509.ParseCertificate(caDER)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) (*x509.Certificate, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("issue %s: %v", name, err)
+		}
+		cert, _ := x509.ParseCertificate(der)
+		return cert, key
+	}
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		return path
+	}
+
+	serverCert, serverKey := issue(2, "server", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := issue(3, "scanner", x509.ExtKeyUsageClientAuth)
+
+	caPath := writePEM("ca.pem", "CERTIFICATE", caDER)
+	clientCertPath := writePEM("client.crt", "CERTIFICATE", clientCert.Raw)
+	clientKeyDER, _ := x509.MarshalPKCS8PrivateKey(clientKey)
+	clientKeyPath := writePEM("client.key", "PRIVATE KEY", clientKeyDER)
+
+	pfx, err := pkcs12.Modern.Encode(clientKey, clientCert, []*x509.Certificate{caCert}, "changeit")
+	if err != nil {
+		t.Fatalf("encode PKCS#12: %v", err)
+	}
+	pfxPath := filepath.Join(dir, "client.p12")
+	if err := os.WriteFile(pfxPath, pfx, 0o600); err != nil {
+		t.Fatalf("write PKCS#12: %v", err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(caCert)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<title>" + r.TLS.PeerCertificates[0].Subject.CommonName + "</title>"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	cases := []struct {
+		name    string
+		request ScanRequest
+		wantOK  bool
+	}{
+		{"no client certificate", ScanRequest{CABundlePath: caPath}, false},
+		{"PEM certificate and key", ScanRequest{CABundlePath: caPath, ClientCertPath: clientCertPath, ClientKeyPath: clientKeyPath}, true},
+		{"PKCS#12 bundle", ScanRequest{CABundlePath: caPath, ClientCertPath: pfxPath, ClientCertPassword: "changeit"}, true},
+		{"insecure without CA", ScanRequest{InsecureSkipVerify: true, ClientCertPath: pfxPath, ClientCertPassword: "changeit"}, true},
+		{"untrusted server", ScanRequest{ClientCertPath: pfxPath, ClientCertPassword: "changeit"}, false},
+	}
+	for _, tc := range cases {
+		tc.request.Concurrency = 1
+		tc.request.TimeoutSeconds = 5
+		rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL)}, tc.request, nil)
+		if err != nil {
+			t.Fatalf("%s: runScanWorkers returned error: %v", tc.name, err)
+		}
+		if ok := rows[0].Error == "" && rows[0].Title == "scanner"; ok != tc.wantOK {
+			t.Fatalf("%s: expected success=%v, got %+v", tc.name, tc.wantOK, rows[0])
+		}
+	}
+
+	// The replay proxy is reached with the same TLS settings as the targets.
+	replayed, err := replayHits(context.Background(), []ScanRow{{URL: "http://target.invalid/ok", StatusCode: 200}}, ScanRequest{
+		TimeoutSeconds: 5,
+		ReplayProxyURL: server.URL,
+		CABundlePath:   caPath,
+		ClientCertPath: clientCertPath,
+		ClientKeyPath:  clientKeyPath,
+	})
+	if err != nil || replayed != 1 {
+		t.Fatalf("expected the replay to present the client certificate, got %d, %v", replayed, err)
+	}
+
+	if _, err := newTLSConfig(ScanRequest{ClientCertPath: pfxPath, ClientCertPassword: "wrong"}); err == nil {
+		t.Fatal("expected wrong PKCS#12 password to be rejected")
+	}
+	if _, err := newTLSConfig(ScanRequest{CABundlePath: clientKeyPath}); err == nil {
+		t.Fatal("expected a CA bundle without certificates to be rejected")
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sync"
+)
+
+const (
+	wildcardProbeCount      = 3
+	wildcardMinLengthSlack  = 64
+	wildcardLengthSlackRate = 0.05
+	wildcardWordSlack       = 3
+)
+
+// responseSignature is the part of a response used to recognise a host's
+// catch-all page.
+type responseSignature struct {
+	status   int
+	length   int64
+	words    int
+	title    string
+	bodyHash string
+}
+
+func signatureOf(row ScanRow) responseSignature {
+	return responseSignature{
+		status:   row.StatusCode,
+		length:   row.ContentLength,
+		words:    row.Words,
+		title:    row.Title,
+		bodyHash: row.bodyHash,
+	}
+}
+
+// matches reports whether row looks like the same page as the baseline: the
+// same status and either an identical body, or the same title with a length
+// and word count that only differ by what a reflected path would add.
+func (s responseSignature) matches(row ScanRow) bool {
+	if row.StatusCode != s.status {
+		return false
+	}
+	if row.bodyHash != "" && row.bodyHash == s.bodyHash {
+		return true
+	}
+	if row.Title != s.title {
+		return false
+	}
+
+	slack := int64(float64(s.length) * wildcardLengthSlackRate)
+	if slack < wildcardMinLengthSlack {
+		slack = wildcardMinLengthSlack
+	}
+	return absInt64(row.ContentLength-s.length) <= slack && absInt(row.Words-s.words) <= wildcardWordSlack
+}
+
+// wildcardDetector probes every host once with random paths and remembers the
+// responses as that host's baseline. Hosts that answer the probes with a real
+// 404 (or not at all) get an empty baseline and never match.
+type wildcardDetector struct {
+	client  *http.Client
+	options scanOptions
+
+	mu    sync.Mutex
+	hosts map[string]*hostBaseline
+}
+
+type hostBaseline struct {
+	once       sync.Once
+	signatures []responseSignature
+}
+
+func newWildcardDetector(client *http.Client, options scanOptions) *wildcardDetector {
+	return &wildcardDetector{client: client, options: options, hosts: make(map[string]*hostBaseline)}
+}
+
+// Matches probes the row's host on first use and reports whether the row
+// matches the host's baseline.
+func (d *wildcardDetector) Matches(ctx context.Context, row ScanRow) bool {
+	if row.StatusCode == 0 || row.StatusCode == http.StatusNotFound {
+		return false
+	}
+
+	parsed, err := url.Parse(row.URL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+
+	baseline := d.baseline(origin)
+	baseline.once.Do(func() {
+		baseline.signatures = d.probe(ctx, origin)
+	})
+
+	for _, signature := range baseline.signatures {
+		if signature.matches(row) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *wildcardDetector) baseline(origin string) *hostBaseline {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	baseline, ok := d.hosts[origin]
+	if !ok {
+		baseline = &hostBaseline{}
+		d.hosts[origin] = baseline
+	}
+	return baseline
+}
+
+// probe requests a few random paths on origin. Each probe waits for the
+// scheduler like any other request to the host.
+func (d *wildcardDetector) probe(ctx context.Context, origin string) []responseSignature {
+	suffixes := []string{"", ".html", "/"}
+	signatures := make([]responseSignature, 0, wildcardProbeCount)
+	for i := 0; i < wildcardProbeCount; i++ {
+		probeURL := origin + "/" + randomToken() + suffixes[i%len(suffixes)]
+		if d.options.scheduler.wait(ctx, probeURL) != nil {
+			break
+		}
+		row := scanURL(ctx, d.client, probeURL, d.options)
+		if row.StatusCode == 0 || row.StatusCode == http.StatusNotFound {
+			continue
+		}
+		signatures = append(signatures, signatureOf(row))
+	}
+	return signatures
+}
+
+// applyWildcardMatches counts rows flagged as wildcard responses and, when
+// drop is set, removes them.
+func applyWildcardMatches(rows []ScanRow, drop bool) ([]ScanRow, int) {
+	kept := make([]ScanRow, 0, len(rows))
+	matches := 0
+	for _, row := range rows {
+		if row.WildcardMatch {
+			matches++
+			if drop {
+				continue
+			}
+		}
+		kept = append(kept, row)
+	}
+	return kept, matches
+}
+
+func randomToken() string {
+	buffer := make([]byte, 8)
+	_, _ = rand.Read(buffer)
+	return hex.EncodeToString(buffer)
+}
+
+func absInt64(value int64) int64 {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
+
+func absInt(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
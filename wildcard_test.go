@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunScanWorkersFlagsWildcardResponses(t *testing.T) {
+	catchAll := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/admin" {
+			_, _ = w.Write([]byte("<html><head><title>Admin Console</title></head><body>welcome back, administrator</body></html>"))
+			return
+		}
+		// A soft-404 page that reflects the requested path.
+		_, _ = fmt.Fprintf(w, "<html><head><title>Page Not Found</title></head><body>Sorry, %s does not exist.</body></html>", r.URL.Path)
+	}))
+	defer catchAll.Close()
+
+	strict := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/index" {
+			_, _ = w.Write([]byte("<title>Home</title>"))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer strict.Close()
+
+	entries := []inputEntry{
+		urlEntry(catchAll.URL + "/admin"),
+		urlEntry(catchAll.URL + "/backup.zip"),
+		urlEntry(catchAll.URL + "/some/deeper/path"),
+		urlEntry(strict.URL + "/index"),
+	}
+	rows, err := runScanWorkers(context.Background(), entries, ScanRequest{
+		Concurrency:    4,
+		TimeoutSeconds: 5,
+		DetectWildcard: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+
+	expected := []bool{false, true, true, false}
+	for i, row := range rows {
+		if row.WildcardMatch != expected[i] {
+			t.Fatalf("expected wildcard=%v for %s, got %v", expected[i], row.URL, row.WildcardMatch)
+		}
+	}
+
+	kept, matches := applyWildcardMatches(rows, true)
+	if matches != 2 || len(kept) != 2 {
+		t.Fatalf("expected 2 wildcard matches dropped, got matches=%d kept=%d", matches, len(kept))
+	}
+}
+
+func TestWildcardProbesHonourTheRateLimit(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		times []time.Time
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		times = append(times, time.Now())
+		mu.Unlock()
+		_, _ = w.Write([]byte("<title>catch-all</title>"))
+	}))
+	defer server.Close()
+
+	rows, err := runScanWorkers(context.Background(), []inputEntry{urlEntry(server.URL + "/a")}, ScanRequest{
+		Concurrency:       1,
+		TimeoutSeconds:    5,
+		RequestsPerSecond: 20,
+		DetectWildcard:    true,
+		DisableFavicon:    true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("runScanWorkers returned error: %v", err)
+	}
+	if len(rows) != 1 || !rows[0].WildcardMatch {
+		t.Fatalf("expected the row to match the catch-all page, got %+v", rows)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(times) != 1+wildcardProbeCount {
+		t.Fatalf("expected the request and %d probes, got %d requests", wildcardProbeCount, len(times))
+	}
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < 40*time.Millisecond {
+			t.Fatalf("expected probes to honour 20 requests per second, request %d came %v after the previous one", i, gap)
+		}
+	}
+}